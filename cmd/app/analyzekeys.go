@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"skeleton/internal/configuration"
+	"skeleton/pkg/redis"
+)
+
+// analyzeKeys prints the biggest keys per type and per prefix:
+//
+//	app analyze-keys -match 'user:*' -limit 100000 -top 20
+func analyzeKeys(ctx context.Context, cfg *configuration.Configuration, args []string) error {
+	analyzerCfg := cfg.REDIS.Analyzer
+
+	flags := flag.NewFlagSet("analyze-keys", flag.ContinueOnError)
+	flags.StringVar(&analyzerCfg.Match, "match", analyzerCfg.Match, "pattern of keys to sample")
+	flags.IntVar(&analyzerCfg.SampleLimit, "limit", analyzerCfg.SampleLimit, "maximum number of keys to sample, 0 for all")
+	flags.IntVar(&analyzerCfg.TopN, "top", analyzerCfg.TopN, "number of keys to report per type and prefix")
+	flags.StringVar(&analyzerCfg.Separator, "separator", analyzerCfg.Separator, "separator of the key prefix")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := redis.New(&cfg.REDIS, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	report, err := redis.NewAnalyzer(r, analyzerCfg, nil).Analyze(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "scanned keys: %d\n", report.Scanned)
+	printKeys(w, "type", report.ByType)
+	printKeys(w, "prefix", report.ByPrefix)
+
+	return w.Flush()
+}
+
+func printKeys(w *tabwriter.Writer, group string, keys map[string][]redis.KeyInfo) {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "\n%s %s\nKEY\tTYPE\tBYTES\tLENGTH\n", group, name)
+		for _, info := range keys[name] {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", info.Key, info.Type, info.Bytes, info.Length)
+		}
+	}
+}
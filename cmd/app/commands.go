@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"skeleton/internal/configuration"
+)
+
+type command func(ctx context.Context, cfg *configuration.Configuration, args []string) error
+
+var _commands = map[string]command{
+	"analyze-keys":    analyzeKeys,
+	"export-keys":     exportKeys,
+	"import-keys":     importKeys,
+	"replay-commands": replayCommands,
+}
+
+func runCommand(ctx context.Context, cfg *configuration.Configuration, name string, args []string) error {
+	cmd, ok := _commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	return cmd(ctx, cfg, args)
+}
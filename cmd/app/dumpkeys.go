@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"skeleton/internal/configuration"
+	"skeleton/pkg/redis"
+)
+
+// exportKeys writes the matching keys to a NDJSON file, stdout by
+// default:
+//
+//	app export-keys -match 'user:*' -file users.ndjson.gz -gzip
+func exportKeys(ctx context.Context, cfg *configuration.Configuration, args []string) error {
+	var (
+		opts redis.ExportOptions
+		file string
+	)
+	flags := flag.NewFlagSet("export-keys", flag.ContinueOnError)
+	flags.StringVar(&opts.Match, "match", "*", "pattern of keys to export")
+	flags.Int64Var(&opts.ScanCount, "count", 1000, "COUNT of every SCAN")
+	flags.BoolVar(&opts.Gzip, "gzip", false, "gzip the output")
+	flags.StringVar(&file, "file", "", "output file, stdout when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	r, err := redis.New(&cfg.REDIS, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	exported, err := r.Export(ctx, w, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported keys: %d\n", exported)
+
+	return nil
+}
+
+// importKeys loads a file written by export-keys, stdin by default:
+//
+//	app import-keys -file users.ndjson.gz -mode skip
+func importKeys(ctx context.Context, cfg *configuration.Configuration, args []string) error {
+	var (
+		mode string
+		file string
+	)
+	flags := flag.NewFlagSet("import-keys", flag.ContinueOnError)
+	flags.StringVar(&mode, "mode", string(redis.ImportOverwrite), "overwrite or skip existing keys")
+	flags.StringVar(&file, "file", "", "input file, stdin when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := redis.ImportOptions{Mode: redis.ImportMode(mode)}
+	if opts.Mode != redis.ImportOverwrite && opts.Mode != redis.ImportSkip {
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	r, err := redis.New(&cfg.REDIS, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var rd io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rd = f
+	}
+
+	stats, err := r.Import(ctx, rd, opts)
+	fmt.Fprintf(os.Stderr, "imported keys: %d, skipped keys: %d\n", stats.Imported, stats.Skipped)
+
+	return err
+}
@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"skeleton/internal/application"
 	"skeleton/internal/configuration"
 	"skeleton/internal/factories"
 	"skeleton/internal/repositories/service"
+	"skeleton/pkg/chaos"
+	"skeleton/pkg/prometheus"
 )
 
 func main() {
@@ -18,14 +21,33 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	chaosInjectorFactory := &factories.ChaosInjectorFactory{}
+	chaosInjector, err := chaosInjectorFactory.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := chaos.Serve(cfg.CHAOS.Admin, chaosInjector); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Prometheus.Enabled {
+		prometheus.Init(&cfg.Prometheus)
+	}
+
 	mssqlManagerFactory := &factories.MSSQLManagerFactory{}
-	mssqlManager, err := mssqlManagerFactory.New(ctx, cfg)
+	mssqlManager, err := mssqlManagerFactory.New(ctx, cfg, chaosInjector)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	redisManagerFactory := &factories.REDISManagerFactory{}
-	redisManager, err := redisManagerFactory.New(ctx, cfg)
+	redisManager, err := redisManagerFactory.New(ctx, cfg, chaosInjector)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -50,6 +72,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	keyspaceAnalyzerFactory := &factories.KeyspaceAnalyzerFactory{}
+	keyspaceAnalyzer, err := keyspaceAnalyzerFactory.New(ctx, cfg, redisManager.GetDB())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if keyspaceAnalyzer != nil {
+		app.Register(keyspaceAnalyzer)
+	}
+
 	if err := app.Run(ctx); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"skeleton/internal/configuration"
+	"skeleton/pkg/redis"
+)
+
+// replayCommands re-issues a recording of redis commands with its
+// rotated files against the redis given by -host, which is
+// required so a replay never hits the configured redis by mistake:
+//
+//	app replay-commands -file redis.rec -host localhost:6379 -speed 2
+func replayCommands(ctx context.Context, cfg *configuration.Configuration, args []string) error {
+	var (
+		replayCfg redis.ReplayConfig
+		file      string
+		host      string
+		maxFiles  int
+	)
+	flags := flag.NewFlagSet("replay-commands", flag.ContinueOnError)
+	flags.StringVar(&file, "file", "", "recording file, its rotated files are replayed first")
+	flags.IntVar(&maxFiles, "max-files", 0, "number of rotated files of the recording")
+	flags.StringVar(&host, "host", "", "redis to replay against")
+	flags.Float64Var(&replayCfg.Speed, "speed", 1, "replay speed, 0 to send without waiting")
+	flags.IntVar(&replayCfg.Concurrency, "concurrency", 100, "maximum commands in flight")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	if host == "" {
+		return fmt.Errorf("-host is required")
+	}
+
+	redisCfg := cfg.REDIS
+	redisCfg.Hosts = []string{host}
+	redisCfg.RedisSentinelPrimary = ""
+	r, err := redis.New(&redisCfg, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var readers []io.Reader
+	for _, name := range redis.RecordingFiles(file, maxFiles) {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	stats, err := r.Replay(ctx, io.MultiReader(readers...), replayCfg)
+	fmt.Fprintf(os.Stderr, "sent commands: %d, failed commands: %d, skipped partial writes: %d\n", stats.Sent, stats.Failed, stats.Skipped)
+
+	return err
+}
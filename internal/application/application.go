@@ -24,6 +24,17 @@ func New(
 	}, nil
 }
 
+// Register adds services to the app. A service.ServiceWithDown is
+// also stopped on shutdown.
+func (it *App) Register(services ...service.Service) {
+	for _, s := range services {
+		it.Services = append(it.Services, s)
+		if withDown, ok := s.(service.ServiceWithDown); ok {
+			it.ServicesWithDown = append(it.ServicesWithDown, withDown)
+		}
+	}
+}
+
 func (it *App) Run(ctx context.Context) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
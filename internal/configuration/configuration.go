@@ -1,6 +1,7 @@
 package configuration
 
 import (
+	"skeleton/pkg/chaos"
 	"skeleton/pkg/mssql"
 	"skeleton/pkg/prometheus"
 	"skeleton/pkg/redis"
@@ -20,6 +21,7 @@ type Configuration struct {
 	REDIS      redis.Config      `yaml:"redis"`
 	MSSQL      mssql.Config      `yaml:"mssql"`
 	Prometheus prometheus.Config `yaml:"prometheus"`
+	CHAOS      chaos.Config      `yaml:"chaos"`
 	// Jaeger     jaeger.Config     `yaml:"jaeger"`
 }
 
@@ -0,0 +1,20 @@
+package factories
+
+import (
+	"context"
+	"skeleton/internal/configuration"
+	"skeleton/pkg/chaos"
+	"skeleton/pkg/prometheus"
+)
+
+type ChaosInjectorFactory struct{}
+
+// New returns the fault injector of the CHAOS config. It injects
+// nothing until the config is enabled.
+func (it *ChaosInjectorFactory) New(
+	ctx context.Context,
+	cfg *configuration.Configuration,
+) (*chaos.Injector, error) {
+	metrics := prometheus.NewChaosMetrics(cfg.Prometheus.Service, cfg.Prometheus.Host)
+	return chaos.New(cfg.CHAOS, metrics), nil
+}
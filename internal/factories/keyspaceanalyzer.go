@@ -0,0 +1,28 @@
+package factories
+
+import (
+	"context"
+	"skeleton/internal/configuration"
+	"skeleton/internal/repositories/service"
+	"skeleton/pkg/prometheus"
+	"skeleton/pkg/redis"
+)
+
+type KeyspaceAnalyzerFactory struct{}
+
+// New returns the analyzer of r that exports the biggest and the
+// hottest keys every REDIS.Analyzer.Interval, or nil when the
+// interval is not set. r has to be the client that serves the
+// traffic, hot keys are tracked per client.
+func (it *KeyspaceAnalyzerFactory) New(
+	ctx context.Context,
+	cfg *configuration.Configuration,
+	r *redis.Redis,
+) (service.Service, error) {
+	if cfg.REDIS.Analyzer.Interval <= 0 {
+		return nil, nil
+	}
+
+	metrics := prometheus.NewKeyspaceMetrics(cfg.Prometheus.Service, cfg.Prometheus.Host)
+	return redis.NewAnalyzer(r, cfg.REDIS.Analyzer, metrics), nil
+}
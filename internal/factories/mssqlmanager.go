@@ -4,6 +4,7 @@ import (
 	"context"
 	"skeleton/internal/configuration"
 	"skeleton/internal/infrastructure/mssqlmanager"
+	"skeleton/pkg/chaos"
 )
 
 type MSSQLManagerFactory struct{}
@@ -11,6 +12,7 @@ type MSSQLManagerFactory struct{}
 func (it *MSSQLManagerFactory) New(
 	ctx context.Context,
 	cfg *configuration.Configuration,
+	injector *chaos.Injector,
 ) (*mssqlmanager.MSSQLManager, error) {
-	return mssqlmanager.New(ctx, cfg)
+	return mssqlmanager.New(ctx, cfg, injector)
 }
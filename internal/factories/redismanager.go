@@ -4,6 +4,7 @@ import (
 	"context"
 	"skeleton/internal/configuration"
 	"skeleton/internal/infrastructure/redismanager"
+	"skeleton/pkg/chaos"
 )
 
 type REDISManagerFactory struct{}
@@ -11,6 +12,7 @@ type REDISManagerFactory struct{}
 func (it *REDISManagerFactory) New(
 	ctx context.Context,
 	cfg *configuration.Configuration,
+	injector *chaos.Injector,
 ) (*redismanager.REDISManager, error) {
-	return redismanager.New(ctx, cfg)
+	return redismanager.New(ctx, cfg, injector)
 }
@@ -2,40 +2,27 @@ package mssqlmanager
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
 	"skeleton/internal/configuration"
-
-	_ "github.com/denisenkom/go-mssqldb"
-	"github.com/jmoiron/sqlx"
+	"skeleton/pkg/chaos"
+	"skeleton/pkg/mssql"
 )
 
 type MSSQLManager struct {
-	storage *sqlx.DB
+	storage *mssql.Database
 }
 
 func New(
 	ctx context.Context,
 	cfg *configuration.Configuration,
+	injector *chaos.Injector,
 ) (*MSSQLManager, error) {
-	newDSN := fmt.Sprintf(
-		"sqlserver://%s:%s@%s:%s?database=%s",
-		cfg.MSSQL.Username,
-		cfg.MSSQL.Password,
-		cfg.MSSQL.Address,
-		cfg.MSSQL.Port,
-		cfg.MSSQL.Database,
-	)
-
-	newDriver := "sqlserver"
-
-	newConnection, err := sql.Open(newDriver, newDSN)
+	storage, err := mssql.New(&cfg.MSSQL)
 	if err != nil {
 		return nil, err
 	}
 
 	return &MSSQLManager{
-		storage: sqlx.NewDb(newConnection, newDriver),
+		storage: storage.WithChaos(injector),
 	}, nil
 }
 
@@ -51,10 +38,10 @@ func (it *MSSQLManager) Down(ctx context.Context) error {
 	return it.storage.Close()
 }
 
-func (it *MSSQLManager) GetDB() *sqlx.DB {
+func (it *MSSQLManager) GetDB() *mssql.Database {
 	return it.storage
 }
 
 func (it *MSSQLManager) ping(ctx context.Context) error {
-	return it.storage.PingContext(ctx)
+	return it.storage.DB().PingContext(ctx)
 }
@@ -3,26 +3,26 @@ package redismanager
 import (
 	"context"
 	"skeleton/internal/configuration"
-
-	"github.com/redis/go-redis/v9"
+	"skeleton/pkg/chaos"
+	"skeleton/pkg/redis"
 )
 
 type REDISManager struct {
-	storage *redis.Client
+	storage *redis.Redis
 }
 
 func New(
 	ctx context.Context,
 	cfg *configuration.Configuration,
+	injector *chaos.Injector,
 ) (*REDISManager, error) {
+	storage, err := redis.New(&cfg.REDIS, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return &REDISManager{
-		storage: redis.NewClient(
-			&redis.Options{
-				Addr:     cfg.REDIS.Hosts[0],
-				Password: cfg.REDIS.Password,
-				DB:       0,
-			},
-		),
+		storage: storage.WithChaos(injector),
 	}, nil
 }
 
@@ -35,17 +35,21 @@ func (it *REDISManager) Up(ctx context.Context) error {
 }
 
 func (it *REDISManager) Down(ctx context.Context) error {
-	return it.storage.Close()
+	it.storage.Close()
+
+	return nil
 }
 
-func (it *REDISManager) GetDB() *redis.Client {
+func (it *REDISManager) GetDB() *redis.Redis {
 	return it.storage
 }
 
 func (it *REDISManager) ping(ctx context.Context) error {
-	if err := it.storage.Ping(
+	client := it.storage.GetClient()
+	if err := client.Do(
 		ctx,
-	).Err(); err != nil {
+		client.B().Ping().Build(),
+	).Error(); err != nil {
 		return err
 	}
 
@@ -2,13 +2,12 @@ package mssqlmanager
 
 import (
 	"skeleton/internal/repositories/service"
-
-	"github.com/jmoiron/sqlx"
+	"skeleton/pkg/mssql"
 )
 
 type MSSQLManagerRepo interface {
 	service.ServiceWithDown
 
 	// Here you can define your own methods.
-	GetDB() *sqlx.DB
+	GetDB() *mssql.Database
 }
@@ -0,0 +1,166 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_defaultTimeout = time.Second
+	_faultLatency   = "latency"
+	_faultError     = "error"
+	_faultTimeout   = "timeout"
+	_faultDrop      = "drop"
+)
+
+var (
+	// ErrInjected is returned by calls failed by a rule.
+	ErrInjected = errors.New("chaos: injected error")
+	// ErrDropped is returned by calls whose connection was dropped
+	// by a rule. It wraps net.ErrClosed, so it is handled as a
+	// network error.
+	ErrDropped = errors.Join(errors.New("chaos: connection dropped"), net.ErrClosed)
+)
+
+// metrics is implemented by metrics that count injected faults.
+type metrics interface {
+	IncFault(target, name, fault string)
+}
+
+// Rule injects faults into the calls of Target whose name matches
+// Match, a glob with * and ?. Redis calls are named by the
+// command, e.g. GET, mssql calls by the query name. Rates are
+// probabilities from 0 to 1: latency is added independently, then
+// at most one of drop, timeout and error is picked. A timeout
+// waits for Timeout or until the context is done.
+type Rule struct {
+	Target      string        `yaml:"target" json:"target"`
+	Match       string        `yaml:"match" json:"match"`
+	Latency     time.Duration `yaml:"latency" json:"latency"`
+	LatencyRate float64       `yaml:"latency_rate" json:"latency_rate"`
+	ErrorRate   float64       `yaml:"error_rate" json:"error_rate"`
+	Timeout     time.Duration `yaml:"timeout" json:"timeout"`
+	TimeoutRate float64       `yaml:"timeout_rate" json:"timeout_rate"`
+	DropRate    float64       `yaml:"drop_rate" json:"drop_rate"`
+}
+
+type Config struct {
+	Enabled bool        `env:"CHAOS_ENABLED" yaml:"enabled" json:"enabled"`
+	Rules   []Rule      `yaml:"rules" json:"rules"`
+	Admin   AdminConfig `yaml:"admin" json:"-"`
+}
+
+// AdminConfig serves the admin endpoint of the injector on its own
+// port, see Serve. Requests must carry the Token as a bearer token.
+type AdminConfig struct {
+	Enabled bool   `env:"CHAOS_ADMIN_ENABLED" yaml:"enabled"`
+	Port    string `env:"CHAOS_ADMIN_PORT" yaml:"port"`
+	Token   string `env:"CHAOS_ADMIN_TOKEN" yaml:"token"`
+}
+
+// Injector picks faults for calls by the first matching rule. The
+// config can be replaced at any time, see Set and Handler.
+type Injector struct {
+	cfg     atomic.Pointer[Config]
+	metrics metrics
+}
+
+func New(cfg Config, metrics metrics) *Injector {
+	i := &Injector{metrics: metrics}
+	i.Set(cfg)
+	return i
+}
+
+// Set replaces the config.
+func (i *Injector) Set(cfg Config) {
+	for idx := range cfg.Rules {
+		if cfg.Rules[idx].Timeout <= 0 {
+			cfg.Rules[idx].Timeout = _defaultTimeout
+		}
+	}
+	i.cfg.Store(&cfg)
+}
+
+func (i *Injector) Config() Config {
+	return *i.cfg.Load()
+}
+
+// Fault returns the latency to add to the call and the error it
+// fails with, nil when it goes through. A timeout is returned as
+// the latency and context.DeadlineExceeded.
+func (i *Injector) Fault(target, name string) (time.Duration, error) {
+	cfg := i.cfg.Load()
+	if !cfg.Enabled {
+		return 0, nil
+	}
+
+	for _, rule := range cfg.Rules {
+		if rule.Target != target {
+			continue
+		}
+		if ok, _ := path.Match(rule.Match, name); !ok {
+			continue
+		}
+
+		var latency time.Duration
+		if rule.Latency > 0 && hit(rule.LatencyRate) {
+			latency = rule.Latency
+			i.inc(target, name, _faultLatency)
+		}
+
+		roll := rand.Float64()
+		switch {
+		case roll < rule.DropRate:
+			i.inc(target, name, _faultDrop)
+			return latency, ErrDropped
+		case roll < rule.DropRate+rule.TimeoutRate:
+			i.inc(target, name, _faultTimeout)
+			return latency + rule.Timeout, context.DeadlineExceeded
+		case roll < rule.DropRate+rule.TimeoutRate+rule.ErrorRate:
+			i.inc(target, name, _faultError)
+			return latency, ErrInjected
+		}
+		return latency, nil
+	}
+	return 0, nil
+}
+
+// Inject applies the fault of the call: it waits for the latency
+// and returns the error. It returns the error of ctx when ctx is
+// done first.
+func (i *Injector) Inject(ctx context.Context, target, name string) error {
+	latency, err := i.Fault(target, name)
+	return Apply(ctx, latency, err)
+}
+
+// Apply waits for latency and returns err, or the error of ctx
+// when ctx is done first.
+func Apply(ctx context.Context, latency time.Duration, err error) error {
+	if latency <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return err
+	}
+}
+
+func (i *Injector) inc(target, name, fault string) {
+	if i.metrics != nil {
+		i.metrics.IncFault(target, name, fault)
+	}
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -0,0 +1,97 @@
+package chaos
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+const (
+	_defaultAdminPort = "9095"
+	_adminPattern     = "/chaos"
+)
+
+// Serve serves the admin endpoint of i on /chaos of the admin port
+// in the background. It does nothing unless cfg is enabled, the
+// token is required.
+func Serve(cfg AdminConfig, i *Injector) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Token == "" {
+		return errors.New("Для админки chaos нужно задать токен")
+	}
+	if cfg.Port == "" {
+		cfg.Port = _defaultAdminPort
+	}
+
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(_adminPattern, authorize(cfg.Token, i))
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Error("Ошибка админки chaos", "error", err)
+		}
+	}()
+	return nil
+}
+
+// authorize passes the requests with the bearer token to next.
+func authorize(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP is the admin endpoint of the injector:
+//
+//	GET                  returns the config as JSON
+//	PUT                  replaces the config with the JSON body
+//	POST ?enabled=false  switches the injector on or off
+//
+// Durations in JSON are nanoseconds.
+func (i *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var cfg Config
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cfg.Admin = i.Config().Admin
+		i.Set(cfg)
+		slog.Warn("Конфигурация chaos заменена", "enabled", cfg.Enabled, "rules", len(cfg.Rules))
+	case http.MethodPost:
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cfg := i.Config()
+		cfg.Enabled = enabled
+		i.Set(cfg)
+		slog.Warn("Chaos переключен", "enabled", enabled)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(i.Config()); err != nil {
+		slog.Error("Ошибка записи конфигурации chaos", "error", err)
+	}
+}
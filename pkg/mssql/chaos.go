@@ -0,0 +1,56 @@
+package mssql
+
+import (
+	"context"
+	"time"
+)
+
+const _chaosTarget = "mssql"
+
+// faultInjector is implemented by chaos.Injector.
+type faultInjector interface {
+	Fault(target, name string) (time.Duration, error)
+}
+
+// WithChaos injects the faults of the "mssql" rules of injector
+// into queries, matched by the query name. Failed queries are not
+// sent. QueryRow methods can't return an error of their own, an
+// injected error or drop surfaces as a canceled context on Scan.
+func (d *Database) WithChaos(injector faultInjector) *Database {
+	d.chaos = injector
+	return d
+}
+
+// inject applies the fault of the query name.
+func (d *Database) inject(ctx context.Context, name string) error {
+	if d.chaos == nil {
+		return nil
+	}
+
+	latency, err := d.chaos.Fault(_chaosTarget, name)
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// injectRow applies the fault of a QueryRow call. It returns the
+// context for the query, done when the query has to fail.
+func (d *Database) injectRow(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	err := d.inject(ctx, name)
+	if err == nil {
+		return ctx, func() {}
+	}
+	if err == context.DeadlineExceeded {
+		return context.WithDeadline(ctx, time.Time{})
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	cancel(err)
+	return ctx, func() {}
+}
@@ -17,6 +17,7 @@ type Database struct {
 	dbName     string
 	metrics    metrics
 	connection *sqlx.DB
+	chaos      faultInjector
 }
 
 func New(config *Config) (*Database, error) {
@@ -40,6 +40,10 @@ func (d *Database) sendMetric(start time.Time, name string, status bool) {
 // context, use [DB.QueryContext].
 func (d *Database) Query(name, query string, args ...any) (*sql.Rows, error) {
 	start := time.Now()
+	if err := d.inject(context.Background(), name); err != nil {
+		d.sendMetric(start, name, false)
+		return nil, err
+	}
 	rows, err := d.connection.Query(query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -51,6 +55,10 @@ func (d *Database) Query(name, query string, args ...any) (*sql.Rows, error) {
 // parameters in the query.
 func (d *Database) QueryContext(ctx context.Context, name, query string, args ...any) (*sql.Rows, error) {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		return nil, err
+	}
 	rows, err := d.connection.QueryContext(ctx, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -62,6 +70,10 @@ func (d *Database) QueryContext(ctx context.Context, name, query string, args ..
 // parameters in the query.
 func (d *Database) QueryxContext(ctx context.Context, name, query string, args ...any) (*sqlx.Rows, error) {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		return nil, err
+	}
 	rows, err := d.connection.QueryxContext(ctx, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -74,6 +86,10 @@ func (d *Database) QueryxContext(ctx context.Context, name, query string, args .
 // context, use [DB.ExecContext].
 func (d *Database) Exec(name, query string, args ...any) (sql.Result, error) {
 	start := time.Now()
+	if err := d.inject(context.Background(), name); err != nil {
+		d.sendMetric(start, name, false)
+		return nil, err
+	}
 	result, err := d.connection.Exec(query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -84,6 +100,10 @@ func (d *Database) Exec(name, query string, args ...any) (sql.Result, error) {
 // The args are for any placeholder parameters in the query.
 func (d *Database) ExecContext(ctx context.Context, name, query string, args ...any) (sql.Result, error) {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		return nil, err
+	}
 	result, err := d.connection.ExecContext(ctx, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -94,6 +114,10 @@ func (d *Database) ExecContext(ctx context.Context, name, query string, args ...
 // placeholder parameters are replaced with supplied args.
 func (d *Database) MustExec(name, query string, args ...any) sql.Result {
 	start := time.Now()
+	if err := d.inject(context.Background(), name); err != nil {
+		d.sendMetric(start, name, false)
+		panic(err)
+	}
 	result := d.connection.MustExec(query, args...)
 	d.sendMetric(start, name, false)
 
@@ -105,6 +129,10 @@ func (d *Database) MustExec(name, query string, args ...any) sql.Result {
 // supplied args.
 func (d *Database) MustExecContext(ctx context.Context, name, query string, args ...any) sql.Result {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		panic(err)
+	}
 	result := d.connection.MustExecContext(ctx, query, args...)
 	d.sendMetric(start, name, false)
 
@@ -121,7 +149,9 @@ func (d *Database) MustExecContext(ctx context.Context, name, query string, args
 // the context, use [DB.QueryRowContext].
 func (d *Database) QueryRow(name, query string, args ...any) *sql.Row {
 	start := time.Now()
-	row := d.connection.QueryRow(query, args...)
+	ctx, cancel := d.injectRow(context.Background(), name)
+	defer cancel()
+	row := d.connection.QueryRowContext(ctx, query, args...)
 	d.sendMetric(start, name, false)
 
 	return row
@@ -136,6 +166,8 @@ func (d *Database) QueryRow(name, query string, args ...any) *sql.Row {
 // rest.
 func (d *Database) QueryRowContext(ctx context.Context, name, query string, args ...any) *sql.Row {
 	start := time.Now()
+	ctx, cancel := d.injectRow(ctx, name)
+	defer cancel()
 	row := d.connection.QueryRowContext(ctx, query, args...)
 	d.sendMetric(start, name, false)
 
@@ -147,7 +179,9 @@ func (d *Database) QueryRowContext(ctx context.Context, name, query string, args
 // args.
 func (d *Database) QueryRowx(name, query string, args ...any) *sqlx.Row {
 	start := time.Now()
-	row := d.connection.QueryRowx(query, args...)
+	ctx, cancel := d.injectRow(context.Background(), name)
+	defer cancel()
+	row := d.connection.QueryRowxContext(ctx, query, args...)
 	d.sendMetric(start, name, false)
 
 	return row
@@ -158,6 +192,8 @@ func (d *Database) QueryRowx(name, query string, args ...any) *sqlx.Row {
 // supplied args.
 func (d *Database) QueryRowxContext(ctx context.Context, name, query string, args ...any) *sqlx.Row {
 	start := time.Now()
+	ctx, cancel := d.injectRow(ctx, name)
+	defer cancel()
 	row := d.connection.QueryRowxContext(ctx, query, args...)
 	d.sendMetric(start, name, false)
 
@@ -168,6 +204,10 @@ func (d *Database) QueryRowxContext(ctx context.Context, name, query string, arg
 // replaced with supplied args.
 func (d *Database) Select(name string, dest interface{}, query string, args ...any) error {
 	start := time.Now()
+	if err := d.inject(context.Background(), name); err != nil {
+		d.sendMetric(start, name, false)
+		return err
+	}
 	err := d.connection.Select(dest, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -178,6 +218,10 @@ func (d *Database) Select(name string, dest interface{}, query string, args ...a
 // are replaced with supplied args.
 func (d *Database) SelectContext(ctx context.Context, name string, dest interface{}, query string, args ...any) error {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		return err
+	}
 	err := d.connection.SelectContext(ctx, dest, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -189,6 +233,10 @@ func (d *Database) SelectContext(ctx context.Context, name string, dest interfac
 // result set is empty.
 func (d *Database) Get(name string, dest interface{}, query string, args ...any) error {
 	start := time.Now()
+	if err := d.inject(context.Background(), name); err != nil {
+		d.sendMetric(start, name, false)
+		return err
+	}
 	err := d.connection.Get(dest, query, args...)
 	d.sendMetric(start, name, err == nil)
 
@@ -200,6 +248,10 @@ func (d *Database) Get(name string, dest interface{}, query string, args ...any)
 // result set is empty.
 func (d *Database) GetContext(ctx context.Context, name string, dest interface{}, query string, args ...any) error {
 	start := time.Now()
+	if err := d.inject(ctx, name); err != nil {
+		d.sendMetric(start, name, false)
+		return err
+	}
 	err := d.connection.GetContext(ctx, dest, query, args...)
 	d.sendMetric(start, name, err == nil)
 
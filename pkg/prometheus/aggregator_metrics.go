@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AggregatorMetrics is a struct that allows to write metrics of
+// in-process aggregators of redis counters.
+type AggregatorMetrics struct {
+	buffered *prometheus.GaugeVec
+	flush    *prometheus.HistogramVec
+	dropped  *prometheus.CounterVec
+}
+
+func NewAggregatorMetrics(service, host string) *AggregatorMetrics {
+	constLabels := prometheus.Labels{"app": service, "host": host}
+
+	bufferedCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "aggregator_buffered_deltas",
+			Help:        "How many keys and fields have buffered deltas",
+			ConstLabels: constLabels,
+		},
+		[]string{"aggregator"},
+	)
+
+	flushCollector := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "aggregator_flush_latency",
+		Help:        "How long it took to flush the buffered deltas",
+		ConstLabels: constLabels,
+		Buckets:     DefaultBucket,
+	},
+		[]string{"aggregator", "success"},
+	)
+
+	droppedCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "aggregator_dropped_deltas_count",
+			Help:        "How many deltas were dropped because the buffer was full",
+			ConstLabels: constLabels,
+		},
+		[]string{"aggregator"},
+	)
+
+	prometheus.MustRegister(bufferedCollector, flushCollector, droppedCollector)
+
+	return &AggregatorMetrics{
+		buffered: bufferedCollector,
+		flush:    flushCollector,
+		dropped:  droppedCollector,
+	}
+}
+
+func (h *AggregatorMetrics) SetBuffered(aggregator string, size int) {
+	h.buffered.WithLabelValues(aggregator).Set(float64(size))
+}
+
+// ObserveFlush writes the time elapsed since startTime for the
+// given "aggregator" and "success" fields
+func (h *AggregatorMetrics) ObserveFlush(aggregator string, startTime time.Time, success bool) {
+	h.flush.WithLabelValues(aggregator, strconv.FormatBool(success)).Observe(timeFromStart(startTime))
+}
+
+func (h *AggregatorMetrics) AddDropped(aggregator string, count int) {
+	h.dropped.WithLabelValues(aggregator).Add(float64(count))
+}
@@ -0,0 +1,36 @@
+package prometheus
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheMetrics is a struct that allows to write hits and misses
+// of every tier of a tiered cache.
+type CacheMetrics struct {
+	requests *prometheus.CounterVec
+}
+
+func NewCacheMetrics(service, host string) *CacheMetrics {
+	requestsCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "cache_tier_requests_count",
+			Help:        "How many cache lookups hit or missed each tier",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"cache", "tier", "hit"},
+	)
+
+	prometheus.MustRegister(requestsCollector)
+
+	return &CacheMetrics{
+		requests: requestsCollector,
+	}
+}
+
+// IncTier increases the counter for the given "cache", "tier"
+// and "hit" fields by 1
+func (h *CacheMetrics) IncTier(cache, tier string, hit bool) {
+	h.requests.WithLabelValues(cache, tier, strconv.FormatBool(hit)).Inc()
+}
@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ChaosMetrics is a struct that allows to count the faults
+// injected by chaos rules.
+type ChaosMetrics struct {
+	faults *prometheus.CounterVec
+}
+
+func NewChaosMetrics(service, host string) *ChaosMetrics {
+	faultsCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "chaos_faults_count",
+			Help:        "How many latencies, errors, timeouts and drops were injected",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"target", "name", "fault"},
+	)
+
+	prometheus.MustRegister(faultsCollector)
+
+	return &ChaosMetrics{
+		faults: faultsCollector,
+	}
+}
+
+// IncFault increases the counter for the given "target", "name"
+// and "fault" fields by 1
+func (h *ChaosMetrics) IncFault(target, name, fault string) {
+	h.faults.WithLabelValues(target, name, fault).Inc()
+}
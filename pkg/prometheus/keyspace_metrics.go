@@ -0,0 +1,54 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KeyspaceMetrics is a struct that allows to export the biggest
+// and the hottest redis keys found by the keyspace analyzer.
+type KeyspaceMetrics struct {
+	bigKeys *prometheus.GaugeVec
+	hotKeys *prometheus.GaugeVec
+}
+
+func NewKeyspaceMetrics(service, host string) *KeyspaceMetrics {
+	bigKeysCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "redis_big_key_bytes",
+			Help:        "Memory usage of the biggest sampled redis keys",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"connection", "type", "prefix", "key"},
+	)
+
+	hotKeysCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "redis_hot_key_commands",
+			Help:        "Estimated number of commands issued for the hottest redis keys",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"connection", "key"},
+	)
+
+	prometheus.MustRegister(bigKeysCollector, hotKeysCollector)
+
+	return &KeyspaceMetrics{
+		bigKeys: bigKeysCollector,
+		hotKeys: hotKeysCollector,
+	}
+}
+
+// ResetKeys removes keys of the previous report of the given
+// connection.
+func (h *KeyspaceMetrics) ResetKeys(connection string) {
+	h.bigKeys.DeletePartialMatch(prometheus.Labels{"connection": connection})
+	h.hotKeys.DeletePartialMatch(prometheus.Labels{"connection": connection})
+}
+
+func (h *KeyspaceMetrics) SetBigKey(connection, keyType, prefix, key string, bytes int64) {
+	h.bigKeys.WithLabelValues(connection, keyType, prefix, key).Set(float64(bytes))
+}
+
+func (h *KeyspaceMetrics) SetHotKey(connection, key string, count uint64) {
+	h.hotKeys.WithLabelValues(connection, key).Set(float64(count))
+}
@@ -0,0 +1,38 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LeaderMetrics is a struct that allows to export whether the
+// instance is the leader of an election.
+type LeaderMetrics struct {
+	leader *prometheus.GaugeVec
+}
+
+func NewLeaderMetrics(service, host string) *LeaderMetrics {
+	leaderCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "leader_elected",
+			Help:        "Whether the instance is the leader of the election",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"election"},
+	)
+
+	prometheus.MustRegister(leaderCollector)
+
+	return &LeaderMetrics{
+		leader: leaderCollector,
+	}
+}
+
+// SetLeader sets the gauge for the given "election" to 1 when the
+// instance is the leader and to 0 otherwise
+func (h *LeaderMetrics) SetLeader(election string, leader bool) {
+	value := 0.0
+	if leader {
+		value = 1
+	}
+	h.leader.WithLabelValues(election).Set(value)
+}
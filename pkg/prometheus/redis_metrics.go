@@ -0,0 +1,135 @@
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var PipelineBucket = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// RedisMetrics is a struct that allows to write metrics of redis
+// commands labelled by connection name together with client-side
+// statistics of every connection.
+type RedisMetrics struct {
+	queries    *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	inFlight   *prometheus.GaugeVec
+	pipeline   *prometheus.HistogramVec
+	cache      *prometheus.CounterVec
+	reconnects *prometheus.CounterVec
+}
+
+func NewRedisMetrics(service, host string) *RedisMetrics {
+	constLabels := prometheus.Labels{"app": service, "host": host}
+
+	queriesCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "redis_queries_count",
+			Help:        "How many redis commands processed",
+			ConstLabels: constLabels,
+		},
+		[]string{"connection", "query", "success"},
+	)
+
+	latencyCollector := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "redis_queries_latency",
+		Help:        "How long it took to process the redis command",
+		ConstLabels: constLabels,
+		Buckets:     DefaultBucket,
+	},
+		[]string{"connection", "query", "success"},
+	)
+
+	inFlightCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "redis_in_flight",
+			Help:        "How many redis commands are waiting for a response",
+			ConstLabels: constLabels,
+		},
+		[]string{"connection"},
+	)
+
+	pipelineCollector := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "redis_pipeline_size",
+		Help:        "How many commands were sent in one pipeline",
+		ConstLabels: constLabels,
+		Buckets:     PipelineBucket,
+	},
+		[]string{"connection"},
+	)
+
+	cacheCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "redis_client_cache_count",
+			Help:        "How many client-side cache lookups hit or missed",
+			ConstLabels: constLabels,
+		},
+		[]string{"connection", "hit"},
+	)
+
+	reconnectsCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "redis_reconnects_count",
+			Help:        "How many closed connections were dialed again",
+			ConstLabels: constLabels,
+		},
+		[]string{"connection"},
+	)
+
+	prometheus.MustRegister(
+		queriesCollector,
+		latencyCollector,
+		inFlightCollector,
+		pipelineCollector,
+		cacheCollector,
+		reconnectsCollector,
+	)
+
+	return &RedisMetrics{
+		queries:    queriesCollector,
+		latency:    latencyCollector,
+		inFlight:   inFlightCollector,
+		pipeline:   pipelineCollector,
+		cache:      cacheCollector,
+		reconnects: reconnectsCollector,
+	}
+}
+
+// WriteTimingAndCounter writes metrics of a command issued by an
+// unnamed connection.
+func (h *RedisMetrics) WriteTimingAndCounter(startTime time.Time, query string, success bool) {
+	h.WriteConnectionTimingAndCounter(startTime, "", query, success)
+}
+
+// WriteConnectionTimingAndCounter increases the counter and
+// writes time elapsed since the startTime for the given
+// "connection", "query" and "success" fields.
+func (h *RedisMetrics) WriteConnectionTimingAndCounter(startTime time.Time, connection, query string, success bool) {
+	successStr := strconv.FormatBool(success)
+	h.queries.WithLabelValues(connection, query, successStr).Inc()
+	h.latency.WithLabelValues(connection, query, successStr).Observe(timeFromStart(startTime))
+}
+
+// AddInFlight changes the number of commands waiting for a
+// response by delta.
+func (h *RedisMetrics) AddInFlight(connection string, delta int) {
+	h.inFlight.WithLabelValues(connection).Add(float64(delta))
+}
+
+// ObservePipeline writes the number of commands sent in one
+// pipeline.
+func (h *RedisMetrics) ObservePipeline(connection string, size int) {
+	h.pipeline.WithLabelValues(connection).Observe(float64(size))
+}
+
+// IncCache increases the client-side cache hit or miss counter.
+func (h *RedisMetrics) IncCache(connection string, hit bool) {
+	h.cache.WithLabelValues(connection, strconv.FormatBool(hit)).Inc()
+}
+
+// IncReconnect increases the reconnect counter.
+func (h *RedisMetrics) IncReconnect(connection string) {
+	h.reconnects.WithLabelValues(connection).Inc()
+}
@@ -0,0 +1,33 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SemaphoreMetrics is a struct that allows to export the number
+// of holders of distributed semaphores.
+type SemaphoreMetrics struct {
+	holders *prometheus.GaugeVec
+}
+
+func NewSemaphoreMetrics(service, host string) *SemaphoreMetrics {
+	holdersCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "semaphore_holders",
+			Help:        "How many holders the semaphore had at the last acquire or release",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"semaphore"},
+	)
+
+	prometheus.MustRegister(holdersCollector)
+
+	return &SemaphoreMetrics{
+		holders: holdersCollector,
+	}
+}
+
+// SetHolders sets the gauge for the given "semaphore" field
+func (h *SemaphoreMetrics) SetHolders(semaphore string, holders int64) {
+	h.holders.WithLabelValues(semaphore).Set(float64(holders))
+}
@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ShadowMetrics is a struct that allows to count the outcomes of
+// shadow reads: match, mismatch, error or dropped.
+type ShadowMetrics struct {
+	reads *prometheus.CounterVec
+}
+
+func NewShadowMetrics(service, host string) *ShadowMetrics {
+	readsCollector := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "redis_shadow_reads_count",
+			Help:        "How many shadow reads matched, mismatched, failed or were dropped",
+			ConstLabels: prometheus.Labels{"app": service, "host": host},
+		},
+		[]string{"command", "result"},
+	)
+
+	prometheus.MustRegister(readsCollector)
+
+	return &ShadowMetrics{
+		reads: readsCollector,
+	}
+}
+
+// IncShadow increases the counter for the given "command" and
+// "result" fields by 1
+func (h *ShadowMetrics) IncShadow(command, result string) {
+	h.reads.WithLabelValues(command, result).Inc()
+}
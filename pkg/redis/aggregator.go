@@ -0,0 +1,263 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultAggregatorInterval = time.Second
+	_defaultAggregatorMaxKeys  = 1000
+)
+
+// aggregatorMetrics is implemented by metrics of an Aggregator.
+type aggregatorMetrics interface {
+	SetBuffered(aggregator string, size int)
+	ObserveFlush(aggregator string, startTime time.Time, success bool)
+	AddDropped(aggregator string, count int)
+}
+
+// AggregatorConfig configures an Aggregator. Buffered deltas are
+// flushed every Interval or when MaxKeys keys and fields are
+// buffered. Deltas that don't fit in MaxBuffer while redis is
+// unavailable are dropped, it defaults to ten times MaxKeys.
+type AggregatorConfig struct {
+	Name      string        `yaml:"name"`
+	Interval  time.Duration `yaml:"interval"`
+	MaxKeys   int           `yaml:"max_keys"`
+	MaxBuffer int           `yaml:"max_buffer"`
+}
+
+type aggregateKind int
+
+const (
+	aggregateIncr aggregateKind = iota
+	aggregateHash
+	aggregateZSet
+)
+
+type aggregateKey struct {
+	kind  aggregateKind
+	key   string
+	field string
+}
+
+// Aggregator buffers INCRBY, HINCRBY and ZINCRBY deltas in process
+// and sends their sums in one pipeline. Counters in redis lag
+// behind by up to Interval. Integer deltas are summed as int64,
+// ZINCRBY deltas as float64.
+//
+// Aggregator implements service.ServiceWithDown, deltas are
+// flushed between Up and Down and the remaining ones on Down.
+type Aggregator struct {
+	redis   *Redis
+	cfg     AggregatorConfig
+	metrics aggregatorMetrics
+	flushCh chan struct{}
+
+	mu     sync.Mutex
+	deltas map[aggregateKey]int64
+	scores map[aggregateKey]float64
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewAggregator(r *Redis, cfg AggregatorConfig, metrics aggregatorMetrics) *Aggregator {
+	if cfg.Interval <= 0 {
+		cfg.Interval = _defaultAggregatorInterval
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = _defaultAggregatorMaxKeys
+	}
+	if cfg.MaxBuffer < cfg.MaxKeys {
+		cfg.MaxBuffer = 10 * cfg.MaxKeys
+	}
+
+	return &Aggregator{
+		redis:   r,
+		cfg:     cfg,
+		metrics: metrics,
+		flushCh: make(chan struct{}, 1),
+		deltas:  make(map[aggregateKey]int64),
+		scores:  make(map[aggregateKey]float64),
+	}
+}
+
+// IncrBy buffers INCRBY key delta.
+func (a *Aggregator) IncrBy(key string, delta int64) {
+	a.add(aggregateKey{kind: aggregateIncr, key: key}, delta, 0)
+}
+
+// HIncrBy buffers HINCRBY key field delta.
+func (a *Aggregator) HIncrBy(key, field string, delta int64) {
+	a.add(aggregateKey{kind: aggregateHash, key: key, field: field}, delta, 0)
+}
+
+// ZIncrBy buffers ZINCRBY key delta member.
+func (a *Aggregator) ZIncrBy(key, member string, delta float64) {
+	a.add(aggregateKey{kind: aggregateZSet, key: key, field: member}, 0, delta)
+}
+
+// Flush sends the buffered deltas. Deltas of commands that were
+// not applied, because redis couldn't be dialed or replied with a
+// transient error, are buffered again. Deltas of other failed
+// commands may have been applied and are dropped.
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	deltas, scores := a.deltas, a.scores
+	a.deltas = make(map[aggregateKey]int64, len(deltas))
+	a.scores = make(map[aggregateKey]float64, len(scores))
+	a.mu.Unlock()
+	a.setBuffered(0)
+
+	if len(deltas)+len(scores) == 0 {
+		return nil
+	}
+
+	b := a.redis.conn.B()
+	keys := make([]aggregateKey, 0, len(deltas)+len(scores))
+	cmds := make(rueidis.Commands, 0, len(deltas)+len(scores))
+	for key, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		if key.kind == aggregateHash {
+			cmds = append(cmds, b.Hincrby().Key(key.key).Field(key.field).Increment(delta).Build())
+		} else {
+			cmds = append(cmds, b.Incrby().Key(key.key).Increment(delta).Build())
+		}
+	}
+	for key, score := range scores {
+		if score == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		cmds = append(cmds, b.Zincrby().Key(key.key).Increment(score).Member(key.field).Build())
+	}
+
+	startTime := time.Now()
+	results := a.redis.DoMulti(ctx, cmds...)
+	err := HasError(results)
+	if a.metrics != nil {
+		a.metrics.ObserveFlush(a.cfg.Name, startTime, err == nil)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var dropped int
+	for idx, result := range results {
+		switch {
+		case result.Error() == nil:
+		case unapplied(result):
+			key := keys[idx]
+			a.add(key, deltas[key], scores[key])
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 && a.metrics != nil {
+		a.metrics.AddDropped(a.cfg.Name, dropped)
+	}
+	return err
+}
+
+// unapplied reports whether a failed command certainly didn't
+// change redis, so its delta can be sent again.
+func unapplied(result rueidis.RedisResult) bool {
+	if err := result.NonRedisError(); err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	redisErr, ok := rueidis.IsRedisErr(result.Error())
+	return ok && (redisErr.IsTryAgain() || redisErr.IsLoading() || redisErr.IsClusterDown())
+}
+
+func (a *Aggregator) Up(ctx context.Context) error {
+	a.mu.Lock()
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	a.mu.Unlock()
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The remaining deltas are flushed under the default
+			// policy timeout.
+			if err := a.Flush(context.WithoutCancel(ctx)); err != nil {
+				slog.Error("Ошибка сброса буфера агрегатора при остановке", "error", err, "aggregator", a.cfg.Name)
+			}
+			return nil
+		case <-ticker.C:
+		case <-a.flushCh:
+		}
+
+		if err := a.Flush(ctx); err != nil {
+			slog.Error("Ошибка сброса буфера агрегатора", "error", err, "aggregator", a.cfg.Name)
+		}
+	}
+}
+
+func (a *Aggregator) Down(ctx context.Context) error {
+	a.mu.Lock()
+	cancel, done := a.cancel, a.done
+	a.mu.Unlock()
+
+	if cancel == nil {
+		return a.Flush(ctx)
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// add buffers delta, or score for ZINCRBY keys.
+func (a *Aggregator) add(key aggregateKey, delta int64, score float64) {
+	a.mu.Lock()
+	_, hasDelta := a.deltas[key]
+	_, hasScore := a.scores[key]
+	if !hasDelta && !hasScore && len(a.deltas)+len(a.scores) >= a.cfg.MaxBuffer {
+		a.mu.Unlock()
+		if a.metrics != nil {
+			a.metrics.AddDropped(a.cfg.Name, 1)
+		}
+		return
+	}
+	if key.kind == aggregateZSet {
+		a.scores[key] += score
+	} else {
+		a.deltas[key] += delta
+	}
+	size := len(a.deltas) + len(a.scores)
+	a.mu.Unlock()
+
+	a.setBuffered(size)
+	if size >= a.cfg.MaxKeys {
+		select {
+		case a.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *Aggregator) setBuffered(size int) {
+	if a.metrics != nil {
+		a.metrics.SetBuffered(a.cfg.Name, size)
+	}
+}
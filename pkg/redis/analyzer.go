@@ -0,0 +1,329 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultAnalyzerCount       = 1000
+	_defaultAnalyzerTopN        = 10
+	_defaultAnalyzerSeparator   = ":"
+	_defaultAnalyzerMaxPrefixes = 100
+
+	// _analyzerNoPrefix is the prefix of keys without the separator.
+	_analyzerNoPrefix = "(none)"
+	// _analyzerOtherPrefix is the prefix of keys whose prefix is not
+	// kept, once MaxPrefixes prefixes are.
+	_analyzerOtherPrefix = "(other)"
+)
+
+// keyspaceMetrics is implemented by metrics that export the
+// results of the keyspace analyzer.
+type keyspaceMetrics interface {
+	ResetKeys(connection string)
+	SetBigKey(connection, keyType, prefix, key string, bytes int64)
+	SetHotKey(connection, key string, count uint64)
+}
+
+type AnalyzerConfig struct {
+	Match       string        `yaml:"match"`
+	Count       int64         `yaml:"count"`
+	SampleLimit int           `yaml:"sample_limit"`
+	TopN        int           `yaml:"top_n"`
+	Separator   string        `yaml:"separator"`
+	MaxPrefixes int           `yaml:"max_prefixes"`
+	Interval    time.Duration `yaml:"interval"`
+}
+
+// KeyInfo describes a sampled key. Length is the number of
+// fields, members or elements of the key, or the value length
+// for strings.
+type KeyInfo struct {
+	Key    string
+	Type   string
+	Prefix string
+	Bytes  int64
+	Length int64
+}
+
+// Report is the result of one keyspace analysis. Keys without the
+// separator are reported under the prefix "(none)", keys of the
+// prefixes found after MaxPrefixes under "(other)", so the report
+// doesn't grow with the keyspace.
+type Report struct {
+	Scanned  int
+	ByType   map[string][]KeyInfo
+	ByPrefix map[string][]KeyInfo
+	HotKeys  []KeyCount
+}
+
+// Analyzer samples the keyspace with SCAN, MEMORY USAGE, TYPE
+// and the length command of each type and reports the biggest
+// keys per type and per key prefix. Analyzer implements
+// service.Service and exports every report to metrics when
+// Interval is set.
+type Analyzer struct {
+	redis   *Redis
+	cfg     AnalyzerConfig
+	metrics keyspaceMetrics
+}
+
+func NewAnalyzer(r *Redis, cfg AnalyzerConfig, metrics keyspaceMetrics) *Analyzer {
+	if cfg.Match == "" {
+		cfg.Match = "*"
+	}
+	if cfg.Count <= 0 {
+		cfg.Count = _defaultAnalyzerCount
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = _defaultAnalyzerTopN
+	}
+	if cfg.Separator == "" {
+		cfg.Separator = _defaultAnalyzerSeparator
+	}
+	if cfg.MaxPrefixes <= 0 {
+		cfg.MaxPrefixes = _defaultAnalyzerMaxPrefixes
+	}
+	return &Analyzer{
+		redis:   r,
+		cfg:     cfg,
+		metrics: metrics,
+	}
+}
+
+func (a *Analyzer) Up(ctx context.Context) error {
+	if a.cfg.Interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := a.Analyze(ctx)
+		if err != nil {
+			slog.Error("Ошибка анализа ключей редис", "error", err, "connection", a.redis.connectionName)
+		} else {
+			a.export(report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Analyze samples up to SampleLimit keys matching Match on every
+// node. Hot keys are taken from the connection tracker, which is
+// reset afterwards so that every report covers one window.
+func (a *Analyzer) Analyze(ctx context.Context) (*Report, error) {
+	startTime := time.Now()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		errAll  error
+		scanned int
+		byType  = make(map[string]*topKeys)
+		byPref  = make(map[string]*topKeys)
+	)
+
+	collect := func(infos []KeyInfo) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, info := range infos {
+			if a.cfg.SampleLimit > 0 && scanned >= a.cfg.SampleLimit {
+				return false
+			}
+			scanned++
+			if _, ok := byPref[info.Prefix]; !ok && len(byPref) >= a.cfg.MaxPrefixes {
+				info.Prefix = _analyzerOtherPrefix
+			}
+			a.top(byType, info.Type).add(info)
+			a.top(byPref, info.Prefix).add(info)
+		}
+		return a.cfg.SampleLimit <= 0 || scanned < a.cfg.SampleLimit
+	}
+
+	for _, node := range a.redis.conn.Nodes() {
+		wg.Add(1)
+		go func(node rueidis.Client) {
+			defer wg.Done()
+			if err := a.scanNode(ctx, node, collect); err != nil {
+				mu.Lock()
+				errAll = errors.Join(errAll, err)
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+	a.redis.writeTimingAndCounter(startTime, "redis_analyze", errAll == nil)
+
+	report := &Report{
+		Scanned:  scanned,
+		ByType:   make(map[string][]KeyInfo, len(byType)),
+		ByPrefix: make(map[string][]KeyInfo, len(byPref)),
+	}
+	for keyType, top := range byType {
+		report.ByType[keyType] = top.sorted()
+	}
+	for prefix, top := range byPref {
+		report.ByPrefix[prefix] = top.sorted()
+	}
+	if hotKeys := a.redis.HotKeys(); hotKeys != nil {
+		report.HotKeys = hotKeys.Top()
+		hotKeys.Reset()
+	}
+
+	return report, errAll
+}
+
+func (a *Analyzer) scanNode(ctx context.Context, node rueidis.Client, collect func([]KeyInfo) bool) error {
+	b := a.redis.conn.B()
+	var cursor uint64
+	for {
+		entry, err := a.redis.doOn(ctx, node, b.Scan().Cursor(cursor).Match(a.cfg.Match).Count(a.cfg.Count).Build()).AsScanEntry()
+		if err != nil {
+			return err
+		}
+
+		infos, err := a.describe(ctx, node, entry.Elements)
+		if err != nil {
+			return err
+		}
+		if !collect(infos) {
+			return nil
+		}
+
+		cursor = entry.Cursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// describe fetches type, memory usage and length of keys in two
+// pipelines. Keys removed between the calls are skipped.
+func (a *Analyzer) describe(ctx context.Context, node rueidis.Client, keys []string) ([]KeyInfo, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	b := a.redis.conn.B()
+
+	cmds := make(rueidis.Commands, 0, len(keys)*2)
+	for _, key := range keys {
+		cmds = append(cmds, b.Type().Key(key).Build(), b.MemoryUsage().Key(key).Build())
+	}
+	result := node.DoMulti(ctx, cmds...)
+
+	infos := make([]KeyInfo, 0, len(keys))
+	lengths := make(rueidis.Commands, 0, len(keys))
+	for i, key := range keys {
+		keyType, err := result[i*2].ToString()
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := result[i*2+1].AsInt64()
+		if err != nil && !rueidis.IsRedisNil(err) {
+			return nil, err
+		}
+		if keyType == "none" {
+			continue
+		}
+
+		infos = append(infos, KeyInfo{
+			Key:    key,
+			Type:   keyType,
+			Prefix: a.prefix(key),
+			Bytes:  bytes,
+		})
+		lengths = append(lengths, lengthCompleted(b, keyType, key))
+	}
+
+	for i, length := range node.DoMulti(ctx, lengths...) {
+		infos[i].Length, _ = length.AsInt64()
+	}
+
+	return infos, nil
+}
+
+func lengthCompleted(b rueidis.Builder, keyType, key string) rueidis.Completed {
+	switch keyType {
+	case "hash":
+		return b.Hlen().Key(key).Build()
+	case "zset":
+		return b.Zcard().Key(key).Build()
+	case "set":
+		return b.Scard().Key(key).Build()
+	case "list":
+		return b.Llen().Key(key).Build()
+	case "stream":
+		return b.Xlen().Key(key).Build()
+	default:
+		return b.Strlen().Key(key).Build()
+	}
+}
+
+func (a *Analyzer) prefix(key string) string {
+	if idx := strings.Index(key, a.cfg.Separator); idx > 0 {
+		return key[:idx]
+	}
+	return _analyzerNoPrefix
+}
+
+func (a *Analyzer) top(tops map[string]*topKeys, name string) *topKeys {
+	top, ok := tops[name]
+	if !ok {
+		top = &topKeys{limit: a.cfg.TopN}
+		tops[name] = top
+	}
+	return top
+}
+
+func (a *Analyzer) export(report *Report) {
+	if a.metrics == nil {
+		return
+	}
+	a.metrics.ResetKeys(a.redis.connectionName)
+	for _, infos := range report.ByPrefix {
+		for _, info := range infos {
+			a.metrics.SetBigKey(a.redis.connectionName, info.Type, info.Prefix, info.Key, info.Bytes)
+		}
+	}
+	for _, hot := range report.HotKeys {
+		a.metrics.SetHotKey(a.redis.connectionName, hot.Key, hot.Count)
+	}
+}
+
+// topKeys keeps the limit biggest keys by memory usage.
+type topKeys struct {
+	limit int
+	keys  []KeyInfo
+}
+
+func (t *topKeys) add(info KeyInfo) {
+	t.keys = append(t.keys, info)
+	if len(t.keys) > t.limit*2 {
+		t.keys = t.sorted()
+	}
+}
+
+func (t *topKeys) sorted() []KeyInfo {
+	sort.Slice(t.keys, func(i, j int) bool {
+		return t.keys[i].Bytes > t.keys[j].Bytes
+	})
+	if len(t.keys) > t.limit {
+		t.keys = t.keys[:t.limit]
+	}
+	return t.keys
+}
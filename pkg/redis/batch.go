@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+var ErrBatchNotExecuted = errors.New("batch is not executed")
+
+// Future is the result of a command queued in a Batch. It is
+// available after Batch.Exec returns.
+type Future[T any] struct {
+	value T
+	err   error
+}
+
+func (f *Future[T]) Result() (T, error) {
+	return f.value, f.err
+}
+
+func (f *Future[T]) Value() T {
+	return f.value
+}
+
+func (f *Future[T]) Err() error {
+	return f.err
+}
+
+type batchExecutor func(ctx context.Context, multi rueidis.Commands) ([]rueidis.RedisResult, error)
+
+// Batch queues commands and sends them in one pipeline. Every
+// queued command returns a typed Future, errors are reported per
+// command and the whole batch writes one metric.
+//
+//	b := r.Batch()
+//	f := b.Get(key)
+//	h := b.HGetAll(key2)
+//	err := b.Exec(ctx)
+type Batch struct {
+	builder   rueidis.Builder
+	exec      batchExecutor
+	redis     *Redis
+	cmds      rueidis.Commands
+	resolvers []func(rueidis.RedisResult, error)
+	executed  bool
+}
+
+func (r *Redis) Batch() *Batch {
+	return &Batch{
+		builder: r.conn.B(),
+		exec:    r.pipeline,
+		redis:   r,
+	}
+}
+
+func (r *Multi) Batch() *Batch {
+	return &Batch{
+		builder: r.mainConn.conn.B(),
+		exec:    r.doBatch,
+		redis:   r.mainConn,
+	}
+}
+
+// doBatch sends read-only batches to the main connection only.
+// Batches with writes are mirrored to every connection and the
+// results of the main connection are returned.
+func (r *Multi) doBatch(ctx context.Context, multi rueidis.Commands) ([]rueidis.RedisResult, error) {
+	readOnly := true
+	for _, cmd := range multi {
+		if !cmd.IsReadOnly() {
+			readOnly = false
+			break
+		}
+	}
+	if readOnly {
+		return r.mainConn.DoMulti(ctx, multi...), nil
+	}
+
+	for idx := len(r.conn) - 1; idx > 0; idx-- {
+		results, err := r.conn[idx].pipeline(ctx, rebuild(r.conn[idx], multi))
+		if err == nil {
+			err = HasError(results)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.mainConn.pipeline(ctx, multi)
+}
+
+// Len returns the number of queued commands.
+func (b *Batch) Len() int {
+	return len(b.cmds)
+}
+
+// Exec sends the queued commands and resolves their futures. The
+// returned error joins the errors of all commands except nil
+// replies.
+func (b *Batch) Exec(ctx context.Context) error {
+	if b.executed {
+		return errors.New("batch is already executed")
+	}
+	b.executed = true
+	if len(b.cmds) == 0 {
+		return nil
+	}
+
+	startTime := time.Now()
+	results, err := b.exec(ctx, b.cmds)
+	if results == nil && err != nil {
+		for _, resolve := range b.resolvers {
+			resolve(rueidis.RedisResult{}, err)
+		}
+		b.redis.writeTimingAndCounter(startTime, "redis_batch", false)
+		return err
+	}
+
+	// An under-replicated batch has results and an error.
+	errAll := err
+	for idx, result := range results {
+		b.resolvers[idx](result, nil)
+		if err := result.Error(); err != nil && !rueidis.IsRedisNil(err) {
+			errAll = errors.Join(errAll, err)
+		}
+	}
+	b.redis.writeTimingAndCounter(startTime, "redis_batch", errAll == nil)
+
+	return errAll
+}
+
+func add[T any](b *Batch, cmd rueidis.Completed, parse func(rueidis.RedisResult) (T, error)) *Future[T] {
+	f := &Future[T]{err: ErrBatchNotExecuted}
+	b.cmds = append(b.cmds, cmd)
+	b.resolvers = append(b.resolvers, func(result rueidis.RedisResult, err error) {
+		if err == nil {
+			err = result.Error()
+		}
+		if err != nil {
+			f.err = err
+			return
+		}
+		f.value, f.err = parse(result)
+	})
+	return f
+}
+
+func toString(result rueidis.RedisResult) (string, error) {
+	return result.ToString()
+}
+
+func toInt64(result rueidis.RedisResult) (int64, error) {
+	return result.AsInt64()
+}
+
+func toFloat64(result rueidis.RedisResult) (float64, error) {
+	return result.AsFloat64()
+}
+
+func toBool(result rueidis.RedisResult) (bool, error) {
+	return result.AsBool()
+}
+
+func toArray(result rueidis.RedisResult) ([]rueidis.RedisMessage, error) {
+	return result.ToArray()
+}
+
+func toMap(result rueidis.RedisResult) (map[string]rueidis.RedisMessage, error) {
+	return result.ToMap()
+}
+
+func toError(result rueidis.RedisResult) (struct{}, error) {
+	return struct{}{}, result.Error()
+}
+
+// Do queues an arbitrary command.
+func (b *Batch) Do(cmd rueidis.Completed) *Future[rueidis.RedisMessage] {
+	return add(b, cmd, func(result rueidis.RedisResult) (rueidis.RedisMessage, error) {
+		return result.ToMessage()
+	})
+}
+
+func (b *Batch) Get(key string) *Future[string] {
+	return add(b, b.builder.Get().Key(key).Build(), toString)
+}
+
+func (b *Batch) MGet(keys ...string) *Future[[]rueidis.RedisMessage] {
+	return add(b, b.builder.Mget().Key(keys...).Build(), toArray)
+}
+
+// Set queues SET, without ttl the TTL rule of the key applies.
+func (b *Batch) Set(key, value string, ttl time.Duration) *Future[struct{}] {
+	return add(b, b.redis.SetCompleted(context.Background(), key, value, ttl), toError)
+}
+
+func (b *Batch) Incr(key string) *Future[int64] {
+	return add(b, b.builder.Incr().Key(key).Build(), toInt64)
+}
+
+func (b *Batch) IncrBy(key string, value int64) *Future[int64] {
+	return add(b, b.builder.Incrby().Key(key).Increment(value).Build(), toInt64)
+}
+
+func (b *Batch) Del(keys ...string) *Future[int64] {
+	return add(b, b.builder.Del().Key(keys...).Build(), toInt64)
+}
+
+func (b *Batch) Exists(keys ...string) *Future[bool] {
+	return add(b, b.builder.Exists().Key(keys...).Build(), toBool)
+}
+
+func (b *Batch) Expire(key string, ttl time.Duration) *Future[bool] {
+	return add(b, b.builder.Expire().Key(key).Seconds(int64(ttl/time.Second)).Build(), toBool)
+}
+
+func (b *Batch) ExpireAt(key string, at time.Time) *Future[bool] {
+	return add(b, b.builder.Expireat().Key(key).Timestamp(at.Unix()).Build(), toBool)
+}
+
+func (b *Batch) TTL(key string) *Future[int64] {
+	return add(b, b.builder.Ttl().Key(key).Build(), toInt64)
+}
+
+func (b *Batch) HGet(key, field string) *Future[string] {
+	return add(b, b.builder.Hget().Key(key).Field(field).Build(), toString)
+}
+
+func (b *Batch) HGetAll(key string) *Future[map[string]rueidis.RedisMessage] {
+	return add(b, b.builder.Hgetall().Key(key).Build(), toMap)
+}
+
+func (b *Batch) HMGet(key string, fields ...string) *Future[[]rueidis.RedisMessage] {
+	return add(b, b.builder.Hmget().Key(key).Field(fields...).Build(), toArray)
+}
+
+func (b *Batch) HSet(key, field, value string) *Future[int64] {
+	return add(b, b.builder.Hset().Key(key).FieldValue().FieldValue(field, value).Build(), toInt64)
+}
+
+// HMSet queues HMSET, the TTL rule of the key applies.
+func (b *Batch) HMSet(key string, kvs map[string]string) *Future[struct{}] {
+	return add(b, b.redis.HMSetComplete(key, kvs), toError)
+}
+
+func (b *Batch) HDel(key string, fields ...string) *Future[int64] {
+	return add(b, b.builder.Hdel().Key(key).Field(fields...).Build(), toInt64)
+}
+
+func (b *Batch) HIncrBy(key, field string, value int64) *Future[int64] {
+	return add(b, b.builder.Hincrby().Key(key).Field(field).Increment(value).Build(), toInt64)
+}
+
+func (b *Batch) LPush(key string, values ...string) *Future[int64] {
+	return add(b, b.builder.Lpush().Key(key).Element(values...).Build(), toInt64)
+}
+
+func (b *Batch) RPush(key string, values ...string) *Future[int64] {
+	return add(b, b.builder.Rpush().Key(key).Element(values...).Build(), toInt64)
+}
+
+func (b *Batch) LRange(key string, start, stop int64) *Future[[]rueidis.RedisMessage] {
+	return add(b, b.builder.Lrange().Key(key).Start(start).Stop(stop).Build(), toArray)
+}
+
+func (b *Batch) SAdd(key string, members ...string) *Future[int64] {
+	return add(b, b.builder.Sadd().Key(key).Member(members...).Build(), toInt64)
+}
+
+func (b *Batch) SRem(key string, members ...string) *Future[int64] {
+	return add(b, b.builder.Srem().Key(key).Member(members...).Build(), toInt64)
+}
+
+func (b *Batch) SMembers(key string) *Future[[]rueidis.RedisMessage] {
+	return add(b, b.builder.Smembers().Key(key).Build(), toArray)
+}
+
+func (b *Batch) SIsMember(key, member string) *Future[bool] {
+	return add(b, b.builder.Sismember().Key(key).Member(member).Build(), toBool)
+}
+
+func (b *Batch) ZAdd(key string, score float64, member string) *Future[int64] {
+	return add(b, b.builder.Zadd().Key(key).ScoreMember().ScoreMember(score, member).Build(), toInt64)
+}
+
+func (b *Batch) ZIncrBy(key string, increment float64, member string) *Future[float64] {
+	return add(b, b.builder.Zincrby().Key(key).Increment(increment).Member(member).Build(), toFloat64)
+}
+
+func (b *Batch) ZScore(key, member string) *Future[float64] {
+	return add(b, b.builder.Zscore().Key(key).Member(member).Build(), toFloat64)
+}
+
+func (b *Batch) ZRem(key string, members ...string) *Future[int64] {
+	return add(b, b.builder.Zrem().Key(key).Member(members...).Build(), toInt64)
+}
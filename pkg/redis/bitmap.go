@@ -0,0 +1,214 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+type BitOperation string
+
+const (
+	BitAnd BitOperation = "AND"
+	BitOr  BitOperation = "OR"
+	BitXor BitOperation = "XOR"
+	BitNot BitOperation = "NOT"
+)
+
+// BitFieldOp is a subcommand of BITFIELD. Type is a signed or
+// unsigned integer type like "i8" or "u16", Offset is a bit offset
+// or a "#N" multiple of the type width.
+type BitFieldOp struct {
+	Op     string
+	Type   string
+	Offset string
+	Value  int64
+}
+
+func BitFieldGet(typ, offset string) BitFieldOp {
+	return BitFieldOp{Op: "GET", Type: typ, Offset: offset}
+}
+
+func BitFieldSet(typ, offset string, value int64) BitFieldOp {
+	return BitFieldOp{Op: "SET", Type: typ, Offset: offset, Value: value}
+}
+
+func BitFieldIncrBy(typ, offset string, increment int64) BitFieldOp {
+	return BitFieldOp{Op: "INCRBY", Type: typ, Offset: offset, Value: increment}
+}
+
+func (r *Redis) PFAddCompleted(key string, elements ...string) rueidis.Completed {
+	return r.conn.B().Pfadd().Key(key).Element(elements...).Build()
+}
+
+// PFAdd adds elements to the HyperLogLog at key and reports
+// whether its estimate changed.
+func (r *Redis) PFAdd(ctx context.Context, key string, elements ...string) (bool, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.PFAddCompleted(key, elements...)).AsBool()
+	r.writeTimingAndCounter(startTime, "redis_pfadd", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) PFCountCompleted(keys ...string) rueidis.Completed {
+	return r.conn.B().Pfcount().Key(keys...).Build()
+}
+
+// PFCount returns the estimated number of unique elements in the
+// union of the HyperLogLogs at keys.
+func (r *Redis) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.PFCountCompleted(keys...)).AsInt64()
+	r.writeTimingAndCounter(startTime, "redis_pfcount", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) PFMerge(ctx context.Context, destination string, sources ...string) error {
+	startTime := time.Now()
+	err := r.do(ctx, r.conn.B().Pfmerge().Destkey(destination).Sourcekey(sources...).Build()).Error()
+	r.writeTimingAndCounter(startTime, "redis_pfmerge", err == nil)
+
+	return err
+}
+
+func (r *Redis) SetBitCompleted(key string, offset int64, value bool) rueidis.Completed {
+	bit := int64(0)
+	if value {
+		bit = 1
+	}
+	return r.conn.B().Setbit().Key(key).Offset(offset).Value(bit).Build()
+}
+
+// SetBit sets the bit at offset and returns its previous value.
+func (r *Redis) SetBit(ctx context.Context, key string, offset int64, value bool) (bool, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.SetBitCompleted(key, offset, value)).AsBool()
+	r.writeTimingAndCounter(startTime, "redis_setbit", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) GetBitCompleted(key string, offset int64) rueidis.Completed {
+	return r.conn.B().Getbit().Key(key).Offset(offset).Build()
+}
+
+func (r *Redis) GetBit(ctx context.Context, key string, offset int64) (bool, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.GetBitCompleted(key, offset)).AsBool()
+	r.writeTimingAndCounter(startTime, "redis_getbit", err == nil)
+
+	return result, err
+}
+
+// BitCount returns the number of set bits in key.
+func (r *Redis) BitCount(ctx context.Context, key string) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.conn.B().Bitcount().Key(key).Build()).AsInt64()
+	r.writeTimingAndCounter(startTime, "redis_bitcount", err == nil)
+
+	return result, err
+}
+
+// BitOp stores the result of a bitwise operation over keys in
+// destination and returns its length in bytes. NOT takes one key.
+func (r *Redis) BitOp(ctx context.Context, op BitOperation, destination string, keys ...string) (int64, error) {
+	startTime := time.Now()
+	cmd := r.conn.B().Arbitrary("BITOP").Args(string(op)).Keys(destination).Keys(keys...).Build()
+	result, err := r.do(ctx, cmd).AsInt64()
+	r.writeTimingAndCounter(startTime, "redis_bitop", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) BitFieldCompleted(key string, ops ...BitFieldOp) rueidis.Completed {
+	cmd := r.conn.B().Arbitrary("BITFIELD").Keys(key)
+	for _, op := range ops {
+		cmd = cmd.Args(op.Op, op.Type, op.Offset)
+		if op.Op != "GET" {
+			cmd = cmd.Args(strconv.FormatInt(op.Value, 10))
+		}
+	}
+	return cmd.Build()
+}
+
+// BitField runs ops on the integers stored in key and returns a
+// reply per op.
+func (r *Redis) BitField(ctx context.Context, key string, ops ...BitFieldOp) ([]rueidis.RedisMessage, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.BitFieldCompleted(key, ops...)).ToArray()
+	r.writeTimingAndCounter(startTime, "redis_bitfield", err == nil)
+
+	return result, err
+}
+
+func (r *Multi) PFAddCompleted(key string, elements ...string) rueidis.Completed {
+	return r.mainConn.PFAddCompleted(key, elements...)
+}
+
+func (r *Multi) PFAdd(ctx context.Context, key string, elements ...string) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.PFAdd(ctx, key, elements...)
+	})
+}
+
+func (r *Multi) PFCountCompleted(keys ...string) rueidis.Completed {
+	return r.mainConn.PFCountCompleted(keys...)
+}
+
+func (r *Multi) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return readAll(ctx, r, "PFCount", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.PFCount(ctx, keys...)
+	})
+}
+
+func (r *Multi) PFMerge(ctx context.Context, destination string, sources ...string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.PFMerge(ctx, destination, sources...)
+	})
+}
+
+func (r *Multi) SetBitCompleted(key string, offset int64, value bool) rueidis.Completed {
+	return r.mainConn.SetBitCompleted(key, offset, value)
+}
+
+func (r *Multi) SetBit(ctx context.Context, key string, offset int64, value bool) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.SetBit(ctx, key, offset, value)
+	})
+}
+
+func (r *Multi) GetBitCompleted(key string, offset int64) rueidis.Completed {
+	return r.mainConn.GetBitCompleted(key, offset)
+}
+
+func (r *Multi) GetBit(ctx context.Context, key string, offset int64) (bool, error) {
+	return readAll(ctx, r, "GetBit", func(ctx context.Context, conn *Redis) (bool, error) {
+		return conn.GetBit(ctx, key, offset)
+	})
+}
+
+func (r *Multi) BitCount(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "BitCount", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.BitCount(ctx, key)
+	})
+}
+
+func (r *Multi) BitOp(ctx context.Context, op BitOperation, destination string, keys ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.BitOp(ctx, op, destination, keys...)
+	})
+}
+
+func (r *Multi) BitFieldCompleted(key string, ops ...BitFieldOp) rueidis.Completed {
+	return r.mainConn.BitFieldCompleted(key, ops...)
+}
+
+func (r *Multi) BitField(ctx context.Context, key string, ops ...BitFieldOp) ([]rueidis.RedisMessage, error) {
+	return writeAll(r, func(conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.BitField(ctx, key, ops...)
+	})
+}
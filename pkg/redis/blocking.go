@@ -0,0 +1,156 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+type ListDirection string
+
+const (
+	Left  ListDirection = "LEFT"
+	Right ListDirection = "RIGHT"
+)
+
+// BLPopCompleted builds BLPOP keys timeout. A zero timeout blocks
+// until an element arrives or ctx is done.
+func (r *Redis) BLPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed {
+	return r.conn.B().Blpop().Key(keys...).Timeout(timeout.Seconds()).Build()
+}
+
+// BLPop pops the first element of the first non-empty list and
+// returns the list key with the element. The error is rueidis nil
+// when timeout passes without elements.
+func (r *Redis) BLPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error) {
+	startTime := time.Now()
+	key, value, err := keyValue(r.do(ctx, r.BLPopCompleted(timeout, keys...)))
+	r.writeTimingAndCounter(startTime, "redis_blpop", err == nil)
+
+	return key, value, err
+}
+
+func (r *Redis) BRPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed {
+	return r.conn.B().Brpop().Key(keys...).Timeout(timeout.Seconds()).Build()
+}
+
+// BRPop is BLPop popping from the tail of the lists.
+func (r *Redis) BRPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error) {
+	startTime := time.Now()
+	key, value, err := keyValue(r.do(ctx, r.BRPopCompleted(timeout, keys...)))
+	r.writeTimingAndCounter(startTime, "redis_brpop", err == nil)
+
+	return key, value, err
+}
+
+func (r *Redis) LMoveCompleted(source, destination string, from, to ListDirection) rueidis.Completed {
+	return r.conn.B().Arbitrary("LMOVE").Keys(source, destination).Args(string(from), string(to)).Build()
+}
+
+// LMove atomically moves an element from the from side of source
+// to the to side of destination and returns it.
+func (r *Redis) LMove(ctx context.Context, source, destination string, from, to ListDirection) (string, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.LMoveCompleted(source, destination, from, to)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_lmove", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) BLMoveCompleted(source, destination string, from, to ListDirection, timeout time.Duration) rueidis.Completed {
+	return r.conn.B().Arbitrary("BLMOVE").Keys(source, destination).
+		Args(string(from), string(to), formatSeconds(timeout)).Blocking()
+}
+
+// BLMove is LMove waiting up to timeout for an element in source.
+func (r *Redis) BLMove(ctx context.Context, source, destination string, from, to ListDirection, timeout time.Duration) (string, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.BLMoveCompleted(source, destination, from, to, timeout)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_blmove", err == nil)
+
+	return result, err
+}
+
+func keyValue(result reply) (string, string, error) {
+	values, err := result.AsStrSlice()
+	if err != nil {
+		return "", "", err
+	}
+	if len(values) != 2 {
+		return "", "", rueidis.Nil
+	}
+	return values[0], values[1], nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func (r *Multi) BLPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed {
+	return r.mainConn.BLPopCompleted(timeout, keys...)
+}
+
+// BLPop blocks on the main connection. The popped element is then
+// removed from the other connections without blocking.
+func (r *Multi) BLPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error) {
+	key, value, err := r.mainConn.BLPop(ctx, timeout, keys...)
+	if err != nil {
+		return key, value, err
+	}
+	for _, conn := range r.conn[1:] {
+		if _, err := conn.LPop(ctx, key); err != nil && !rueidis.IsRedisNil(err) {
+			return key, value, err
+		}
+	}
+	return key, value, nil
+}
+
+func (r *Multi) BRPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed {
+	return r.mainConn.BRPopCompleted(timeout, keys...)
+}
+
+// BRPop blocks on the main connection. The popped element is then
+// removed from the other connections without blocking.
+func (r *Multi) BRPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error) {
+	key, value, err := r.mainConn.BRPop(ctx, timeout, keys...)
+	if err != nil {
+		return key, value, err
+	}
+	for _, conn := range r.conn[1:] {
+		if _, err := conn.RPop(ctx, key); err != nil && !rueidis.IsRedisNil(err) {
+			return key, value, err
+		}
+	}
+	return key, value, nil
+}
+
+func (r *Multi) LMoveCompleted(source, destination string, from, to ListDirection) rueidis.Completed {
+	return r.mainConn.LMoveCompleted(source, destination, from, to)
+}
+
+func (r *Multi) LMove(ctx context.Context, source, destination string, from, to ListDirection) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.LMove(ctx, source, destination, from, to)
+	})
+}
+
+func (r *Multi) BLMoveCompleted(source, destination string, from, to ListDirection, timeout time.Duration) rueidis.Completed {
+	return r.mainConn.BLMoveCompleted(source, destination, from, to, timeout)
+}
+
+// BLMove blocks on the main connection. The move is then repeated
+// on the other connections without blocking.
+func (r *Multi) BLMove(ctx context.Context, source, destination string, from, to ListDirection, timeout time.Duration) (string, error) {
+	result, err := r.mainConn.BLMove(ctx, source, destination, from, to, timeout)
+	if err != nil {
+		return result, err
+	}
+	for _, conn := range r.conn[1:] {
+		if _, err := conn.LMove(ctx, source, destination, from, to); err != nil && !rueidis.IsRedisNil(err) {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultBloomCapacity = 1_000_000
+	_defaultBloomRate     = 0.01
+	_maxBloomBits         = 1 << 32
+)
+
+// BloomFilterConfig sizes a filter for Capacity items with the
+// target FalsePositiveRate.
+type BloomFilterConfig struct {
+	Name              string  `yaml:"name"`
+	Capacity          uint64  `yaml:"capacity"`
+	FalsePositiveRate float64 `yaml:"false_positive_rate"`
+}
+
+// BloomFilter is a Bloom filter on a redis bitmap, it doesn't
+// need the RedisBloom module. The bitmap size and the number of
+// hash functions are derived from the config, so every instance
+// with the same config uses the same bits.
+type BloomFilter struct {
+	redis  *Redis
+	key    string
+	bits   uint64
+	hashes int
+}
+
+func NewBloomFilter(r *Redis, cfg BloomFilterConfig) *BloomFilter {
+	if cfg.Capacity == 0 {
+		cfg.Capacity = _defaultBloomCapacity
+	}
+	if cfg.FalsePositiveRate <= 0 || cfg.FalsePositiveRate >= 1 {
+		cfg.FalsePositiveRate = _defaultBloomRate
+	}
+
+	bits, hashes := BloomSize(cfg.Capacity, cfg.FalsePositiveRate)
+	return &BloomFilter{
+		redis:  r,
+		key:    "bloom:" + cfg.Name,
+		bits:   bits,
+		hashes: hashes,
+	}
+}
+
+// BloomSize returns the number of bits m = -n*ln(p)/ln(2)^2 and
+// hash functions k = m/n*ln(2) for n items and false-positive
+// rate p. The bitmap is limited by the maximum redis string size.
+func BloomSize(capacity uint64, rate float64) (bits uint64, hashes int) {
+	m := math.Ceil(-float64(capacity) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	bits = uint64(min(max(m, 1), _maxBloomBits))
+	hashes = max(int(math.Round(float64(bits)/float64(capacity)*math.Ln2)), 1)
+	return bits, hashes
+}
+
+// Bits returns the size of the bitmap.
+func (f *BloomFilter) Bits() uint64 {
+	return f.bits
+}
+
+// Hashes returns the number of hash functions.
+func (f *BloomFilter) Hashes() int {
+	return f.hashes
+}
+
+// Add adds item and reports whether it was not in the filter
+// before.
+func (f *BloomFilter) Add(ctx context.Context, item string) (bool, error) {
+	offsets := f.offsets(item)
+	cmds := make(rueidis.Commands, len(offsets))
+	for idx, offset := range offsets {
+		cmds[idx] = f.redis.SetBitCompleted(f.key, offset, true)
+	}
+
+	startTime := time.Now()
+	results := f.redis.DoMulti(ctx, cmds...)
+	added, err := anyUnset(results)
+	f.redis.writeTimingAndCounter(startTime, "redis_bloom_add", err == nil)
+
+	return added, err
+}
+
+// Contains reports whether item may be in the filter. False
+// means the item was never added.
+func (f *BloomFilter) Contains(ctx context.Context, item string) (bool, error) {
+	offsets := f.offsets(item)
+	cmds := make(rueidis.Commands, len(offsets))
+	for idx, offset := range offsets {
+		cmds[idx] = f.redis.GetBitCompleted(f.key, offset)
+	}
+
+	startTime := time.Now()
+	results := f.redis.DoMulti(ctx, cmds...)
+	missing, err := anyUnset(results)
+	f.redis.writeTimingAndCounter(startTime, "redis_bloom_contains", err == nil)
+
+	return !missing && err == nil, err
+}
+
+// Reset removes all items.
+func (f *BloomFilter) Reset(ctx context.Context) error {
+	_, err := f.redis.Del(ctx, f.key)
+	return err
+}
+
+// offsets returns the bits of item using double hashing of the
+// two halves of its FNV-128a hash.
+func (f *BloomFilter) offsets(item string) []int64 {
+	h := fnv.New128a()
+	h.Write([]byte(item))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:])
+
+	offsets := make([]int64, f.hashes)
+	for idx := range offsets {
+		offsets[idx] = int64((h1 + uint64(idx)*h2) % f.bits)
+	}
+	return offsets
+}
+
+// anyUnset reports whether any of the SETBIT or GETBIT replies
+// is 0.
+func anyUnset(results []rueidis.RedisResult) (bool, error) {
+	if err := HasError(results); err != nil {
+		return false, err
+	}
+	for _, result := range results {
+		if value, _ := result.AsBool(); !value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const _chaosTarget = "redis"
+
+// faultInjector is implemented by chaos.Injector.
+type faultInjector interface {
+	Fault(target, name string) (time.Duration, error)
+}
+
+// WithChaos injects the faults of the "redis" rules of injector
+// into commands, cached commands and pipelines. Commands are
+// matched by name, e.g. GET. Failed commands are not sent. Typed
+// commands return the injected error, failed commands of pipelines
+// and cached reads return context.DeadlineExceeded, since a
+// rueidis result can't carry another error. Dedicated connections,
+// pub/sub and WAIT are not affected.
+func (r *Redis) WithChaos(injector faultInjector) *Redis {
+	r.chaos = injector
+	return r
+}
+
+func (r *Multi) WithChaos(injector faultInjector) *Multi {
+	for _, conn := range r.conn {
+		conn.WithChaos(injector)
+	}
+	return r
+}
+
+// inject applies the fault of the command name.
+func (r *Redis) inject(ctx context.Context, name string) error {
+	if r.chaos == nil {
+		return nil
+	}
+	latency, err := r.chaos.Fault(_chaosTarget, name)
+	if latency > 0 && !sleep(ctx, latency) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// injectMulti applies the faults of a pipeline of commands named
+// by names. The pipeline waits for the biggest latency once. It
+// returns the error of every command, or nil when none fails.
+func (r *Redis) injectMulti(ctx context.Context, names func(idx int) string, n int) []error {
+	if r.chaos == nil {
+		return nil
+	}
+
+	var (
+		latency time.Duration
+		errs    = make([]error, n)
+		failed  bool
+	)
+	for idx := range n {
+		cmdLatency, err := r.chaos.Fault(_chaosTarget, names(idx))
+		latency = max(latency, cmdLatency)
+		errs[idx] = err
+		failed = failed || err != nil
+	}
+	if latency > 0 && !sleep(ctx, latency) {
+		for idx := range errs {
+			errs[idx] = ctx.Err()
+		}
+		return errs
+	}
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+// splitFaults returns the commands without an error to send and
+// the failed ones.
+func splitFaults[T any](cmds []T, errs []error) (send, failed []T) {
+	if errs == nil {
+		return cmds, nil
+	}
+
+	send = make([]T, 0, len(cmds))
+	for idx, cmd := range cmds {
+		if errs[idx] == nil {
+			send = append(send, cmd)
+		} else {
+			failed = append(failed, cmd)
+		}
+	}
+	return send, failed
+}
+
+// fail returns failed results of cmds without sending them: they
+// run under an expired deadline, rueidis checks it first.
+func (r *Redis) fail(cmds rueidis.Commands) []rueidis.RedisResult {
+	if len(cmds) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Time{})
+	defer cancel()
+	return r.conn.DoMulti(ctx, cmds...)
+}
+
+// cacheFailed converts failed cached commands for fail.
+func cacheFailed(cmds []rueidis.CacheableTTL) rueidis.Commands {
+	failed := make(rueidis.Commands, len(cmds))
+	for idx, cmd := range cmds {
+		failed[idx] = rueidis.Completed(cmd.Cmd)
+	}
+	return failed
+}
+
+// mergeFaults puts the results of the sent and of the failed
+// commands in the order of the pipeline.
+func mergeFaults(errs []error, sent, failed []rueidis.RedisResult) []rueidis.RedisResult {
+	if errs == nil {
+		return sent
+	}
+
+	results := make([]rueidis.RedisResult, len(errs))
+	for idx, err := range errs {
+		if err != nil {
+			results[idx], failed = failed[0], failed[1:]
+			continue
+		}
+		results[idx], sent = sent[0], sent[1:]
+	}
+	return results
+}
@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Client is the command set shared by Redis and Multi.
+type Client interface {
+	Exists(ctx context.Context, key ...string) (bool, error)
+	Get(ctx context.Context, key string) (string, error)
+	GetMulti(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error)
+	SetCompleted(ctx context.Context, key, value string, ttl time.Duration) rueidis.Completed
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Del(ctx context.Context, key string) (int64, error)
+	DelCompleted(key string) rueidis.Completed
+	DelMulti(ctx context.Context, keys ...string) (int64, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	ExpireAt(ctx context.Context, key string, at time.Time) error
+	ExpireAtCompleted(key string, at time.Time) rueidis.Completed
+	TTL(ctx context.Context, key string) (int64, error)
+	PTTL(ctx context.Context, key string) (int64, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+	GetRange(ctx context.Context, key string, start, end int64) (string, error)
+	SetRange(ctx context.Context, key string, offset int64, value string) (int64, error)
+	StrLen(ctx context.Context, key string) (int64, error)
+	MGet(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error)
+	MSet(ctx context.Context, kvs map[string]string) error
+	HGetCompleted(key, field string) rueidis.Completed
+	HGet(ctx context.Context, key, field string) (string, error)
+	HSet(ctx context.Context, key, field, value string) (int64, error)
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	HDelCompleted(ctx context.Context, key string, fields ...string) rueidis.Completed
+	HGetAll(ctx context.Context, key string) (map[string]rueidis.RedisMessage, error)
+	HIncrBy(ctx context.Context, key, field string, value int64) (int64, error)
+	HKeys(ctx context.Context, key string) ([]rueidis.RedisMessage, error)
+	HLen(ctx context.Context, key string) (int64, error)
+	HMGet(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error)
+	HMSet(ctx context.Context, key string, kvs map[string]string) error
+	HMSetComplete(key string, kvs map[string]string) rueidis.Completed
+	HSetNX(ctx context.Context, key, field, value string) (int64, error)
+	HVals(ctx context.Context, key string) ([]rueidis.RedisMessage, error)
+	LIndex(ctx context.Context, key string, index int64) (string, error)
+	LInsert(ctx context.Context, key, pivot, value string, before bool) (int64, error)
+	LLen(ctx context.Context, key string) (int64, error)
+	LPop(ctx context.Context, key string) (string, error)
+	LPush(ctx context.Context, key string, values ...string) (int64, error)
+	LPushX(ctx context.Context, key, value string) (int64, error)
+	LRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error)
+	LRem(ctx context.Context, key string, count int64, value string) (int64, error)
+	LSet(ctx context.Context, key string, index int64, value string) error
+	LTrim(ctx context.Context, key string, start, stop int64) error
+	RPop(ctx context.Context, key string) (string, error)
+	RPush(ctx context.Context, key string, values ...string) (int64, error)
+	RPushX(ctx context.Context, key, value string) (int64, error)
+	SAdd(ctx context.Context, key string, members ...string) (int64, error)
+	SAddCompleted(key string, members ...string) rueidis.Completed
+	SCard(ctx context.Context, key string) (int64, error)
+	SDiff(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error)
+	SInter(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error)
+	SIsMember(ctx context.Context, key, member string) (bool, error)
+	SMembers(ctx context.Context, key string) ([]rueidis.RedisMessage, error)
+	SMembersCompleted(key string) rueidis.Completed
+	SMove(ctx context.Context, source, destination, member string) (bool, error)
+	SPop(ctx context.Context, key string) (string, error)
+	SRandMember(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error)
+	SRem(ctx context.Context, key string, members ...string) (int64, error)
+	SUnion(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error)
+	ZAddXX(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZAddNX(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZAddCh(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZAdd(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZCard(ctx context.Context, key string) (int64, error)
+	ZCount(ctx context.Context, key, min, max string) (int64, error)
+	ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error)
+	ZInterStore(ctx context.Context, destination, key string, numkeys int64) (int64, error)
+	ZLexCount(ctx context.Context, key, min, max string) (int64, error)
+	ZPopMax(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error)
+	ZPopMin(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error)
+	ZRange(ctx context.Context, key, start, stop string) ([]rueidis.RedisMessage, error)
+	ZRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error)
+	ZRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error)
+	ZRank(ctx context.Context, key, member string) (int64, error)
+	ZRem(ctx context.Context, key string, members ...string) (int64, error)
+	ZRemRangeByLex(ctx context.Context, key, min, max string) (int64, error)
+	ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error)
+	ZRemRangeByScore(ctx context.Context, key, min, max string) (int64, error)
+	ZRevRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error)
+	ZRevRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error)
+	ZRevRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error)
+	ZRevRank(ctx context.Context, key, member string) (int64, error)
+	ZScore(ctx context.Context, key, member string) (float64, error)
+	ZUnionStore(ctx context.Context, destination string, keys ...string) (int64, error)
+	Close()
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	Scan(ctx context.Context, cursor uint64, match string, count int64) (uint64, []string, error)
+	ScanAllKeys(ctx context.Context, match string, count int64) (map[string]struct{}, error)
+	ScanAllFields(ctx context.Context, key string, fieldMatch string, count int64) ([]string, error)
+	SScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error)
+	HScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error)
+	ZScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error)
+	GetClient() rueidis.Client
+	CTHmget(key string, fields ...string) rueidis.CacheableTTL
+	CTGet(key string) rueidis.CacheableTTL
+	DoMultiCache(ctx context.Context, commands ...rueidis.CacheableTTL) []rueidis.RedisResult
+	DoCache(ctx context.Context, cmd rueidis.CacheableTTL) rueidis.RedisResult
+	GetCompleted(key string) rueidis.Completed
+	HMGetCompleted(ctx context.Context, key string, fields ...string) rueidis.Completed
+	DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult
+	ScanEntryFields(ctx context.Context, key string, fieldMatch string, cursor uint64, count int64) (*rueidis.ScanEntry, error)
+	HGetAllCompleted(ctx context.Context, key string) rueidis.Completed
+	DoMultiExec(ctx context.Context, multi rueidis.Commands) error
+	HotKeys() *HotKeys
+
+	SetNXCompleted(key, value string, ttl time.Duration) rueidis.Completed
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	SetXXCompleted(key, value string, ttl time.Duration) rueidis.Completed
+	SetXX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	SetGetCompleted(key, value string, ttl time.Duration) rueidis.Completed
+	SetGet(ctx context.Context, key, value string, ttl time.Duration) (string, error)
+	SetKeepTTLCompleted(key, value string) rueidis.Completed
+	SetKeepTTL(ctx context.Context, key, value string) error
+	GetExCompleted(key string, ttl time.Duration) rueidis.Completed
+	GetEx(ctx context.Context, key string, ttl time.Duration) (string, error)
+	GetExPersistCompleted(key string) rueidis.Completed
+	GetExPersist(ctx context.Context, key string) (string, error)
+	GetDelCompleted(key string) rueidis.Completed
+	GetDel(ctx context.Context, key string) (string, error)
+	CopyCompleted(source, destination string, replace bool) rueidis.Completed
+	Copy(ctx context.Context, source, destination string, replace bool) (bool, error)
+
+	Batch() *Batch
+
+	BLPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed
+	BLPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error)
+	BRPopCompleted(timeout time.Duration, keys ...string) rueidis.Completed
+	BRPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, error)
+	LMoveCompleted(source, destination string, from, to ListDirection) rueidis.Completed
+	LMove(ctx context.Context, source, destination string, from, to ListDirection) (string, error)
+	BLMoveCompleted(source, destination string, from, to ListDirection, timeout time.Duration) rueidis.Completed
+	BLMove(ctx context.Context, source, destination string, from, to ListDirection, timeout time.Duration) (string, error)
+
+	Eval(ctx context.Context, script *Script, keys, args []string) rueidis.RedisResult
+
+	ZUnionStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed
+	ZUnionStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error)
+	ZInterStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed
+	ZInterStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error)
+	ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]rueidis.ZScore, error)
+
+	PFAddCompleted(key string, elements ...string) rueidis.Completed
+	PFAdd(ctx context.Context, key string, elements ...string) (bool, error)
+	PFCountCompleted(keys ...string) rueidis.Completed
+	PFCount(ctx context.Context, keys ...string) (int64, error)
+	PFMerge(ctx context.Context, destination string, sources ...string) error
+	SetBitCompleted(key string, offset int64, value bool) rueidis.Completed
+	SetBit(ctx context.Context, key string, offset int64, value bool) (bool, error)
+	GetBitCompleted(key string, offset int64) rueidis.Completed
+	GetBit(ctx context.Context, key string, offset int64) (bool, error)
+	BitCount(ctx context.Context, key string) (int64, error)
+	BitOp(ctx context.Context, op BitOperation, destination string, keys ...string) (int64, error)
+	BitFieldCompleted(key string, ops ...BitFieldOp) rueidis.Completed
+	BitField(ctx context.Context, key string, ops ...BitFieldOp) ([]rueidis.RedisMessage, error)
+
+	GeoAddCompleted(key string, locations ...rueidis.GeoLocation) rueidis.Completed
+	GeoAdd(ctx context.Context, key string, locations ...rueidis.GeoLocation) (int64, error)
+	GeoPos(ctx context.Context, key string, members ...string) ([]*rueidis.GeoLocation, error)
+	GeoDist(ctx context.Context, key, member1, member2 string, unit GeoUnit) (float64, error)
+	GeoSearchCompleted(key string, query GeoSearchQuery) rueidis.Completed
+	GeoSearch(ctx context.Context, key string, query GeoSearchQuery) ([]rueidis.GeoLocation, error)
+	GeoSearchStoreCompleted(destination, source string, query GeoSearchQuery, storeDist bool) rueidis.Completed
+	GeoSearchStore(ctx context.Context, destination, source string, query GeoSearchQuery, storeDist bool) (int64, error)
+
+	ProbeHashFieldTTL(ctx context.Context) error
+	HExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed
+	HExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error)
+	HPExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed
+	HPExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error)
+	HExpireAtCompleted(key string, at time.Time, cond ExpireCondition, fields ...string) rueidis.Completed
+	HExpireAt(ctx context.Context, key string, at time.Time, cond ExpireCondition, fields ...string) ([]int64, error)
+	HTTLCompleted(key string, fields ...string) rueidis.Completed
+	HTTL(ctx context.Context, key string, fields ...string) ([]int64, error)
+	HPersistCompleted(key string, fields ...string) rueidis.Completed
+	HPersist(ctx context.Context, key string, fields ...string) ([]int64, error)
+	HGetEx(ctx context.Context, key string, ttl time.Duration, fields ...string) ([]rueidis.RedisMessage, error)
+	HGetExPersist(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error)
+	HSetEx(ctx context.Context, key string, ttl time.Duration, kvs map[string]string) error
+
+	DoDurable(ctx context.Context, d Durability, multi ...rueidis.Completed) ([]rueidis.RedisResult, error)
+}
@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// SetNXCompleted builds SET key value NX [EX ttl].
+func (r *Redis) SetNXCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	if ttl > 0 {
+		return r.conn.B().Set().Key(key).Value(value).Nx().Ex(ttl).Build()
+	}
+	return r.conn.B().Set().Key(key).Value(value).Nx().Build()
+}
+
+// SetNX sets key only if it does not exist and reports whether
+// the write was applied.
+func (r *Redis) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	startTime := time.Now()
+	applied, err := applied(r.do(ctx, r.SetNXCompleted(key, value, ttl)))
+	r.writeTimingAndCounter(startTime, "redis_set_nx", err == nil)
+
+	return applied, err
+}
+
+// SetXXCompleted builds SET key value XX [EX ttl].
+func (r *Redis) SetXXCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	if ttl > 0 {
+		return r.conn.B().Set().Key(key).Value(value).Xx().Ex(ttl).Build()
+	}
+	return r.conn.B().Set().Key(key).Value(value).Xx().Build()
+}
+
+// SetXX sets key only if it already exists and reports whether
+// the write was applied.
+func (r *Redis) SetXX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	startTime := time.Now()
+	applied, err := applied(r.do(ctx, r.SetXXCompleted(key, value, ttl)))
+	r.writeTimingAndCounter(startTime, "redis_set_xx", err == nil)
+
+	return applied, err
+}
+
+// SetGetCompleted builds SET key value GET [EX ttl].
+func (r *Redis) SetGetCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	if ttl > 0 {
+		return r.conn.B().Set().Key(key).Value(value).Get().Ex(ttl).Build()
+	}
+	return r.conn.B().Set().Key(key).Value(value).Get().Build()
+}
+
+// SetGet sets key and returns its old value. The error is
+// rueidis nil when the key did not exist.
+func (r *Redis) SetGet(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	startTime := time.Now()
+	old, err := r.do(ctx, r.SetGetCompleted(key, value, ttl)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_set_get", err == nil)
+
+	return old, err
+}
+
+// SetKeepTTLCompleted builds SET key value KEEPTTL.
+func (r *Redis) SetKeepTTLCompleted(key, value string) rueidis.Completed {
+	return r.conn.B().Set().Key(key).Value(value).Keepttl().Build()
+}
+
+// SetKeepTTL updates the value of key without touching its TTL.
+func (r *Redis) SetKeepTTL(ctx context.Context, key, value string) error {
+	startTime := time.Now()
+	err := r.do(ctx, r.SetKeepTTLCompleted(key, value)).Error()
+	r.writeTimingAndCounter(startTime, "redis_set_keep_ttl", err == nil)
+
+	return err
+}
+
+// GetExCompleted builds GETEX key EX ttl, or GETEX key without
+// options, which leaves the TTL as is, when ttl is not positive.
+func (r *Redis) GetExCompleted(key string, ttl time.Duration) rueidis.Completed {
+	if ttl > 0 {
+		return r.conn.B().Getex().Key(key).Ex(ttl).Build()
+	}
+	return r.conn.B().Getex().Key(key).Build()
+}
+
+// GetEx returns the value of key and refreshes its TTL. A ttl
+// that is not positive doesn't touch the TTL, use GetExPersist to
+// remove it.
+func (r *Redis) GetEx(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	startTime := time.Now()
+	value, err := r.do(ctx, r.GetExCompleted(key, ttl)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_getex", err == nil)
+
+	return value, err
+}
+
+// GetExPersistCompleted builds GETEX key PERSIST.
+func (r *Redis) GetExPersistCompleted(key string) rueidis.Completed {
+	return r.conn.B().Getex().Key(key).Persist().Build()
+}
+
+// GetExPersist returns the value of key and removes its TTL.
+func (r *Redis) GetExPersist(ctx context.Context, key string) (string, error) {
+	startTime := time.Now()
+	value, err := r.do(ctx, r.GetExPersistCompleted(key)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_getex_persist", err == nil)
+
+	return value, err
+}
+
+func (r *Redis) GetDelCompleted(key string) rueidis.Completed {
+	return r.conn.B().Getdel().Key(key).Build()
+}
+
+// GetDel returns the value of key and deletes it.
+func (r *Redis) GetDel(ctx context.Context, key string) (string, error) {
+	startTime := time.Now()
+	value, err := r.do(ctx, r.GetDelCompleted(key)).ToString()
+	r.writeTimingAndCounter(startTime, "redis_getdel", err == nil)
+
+	return value, err
+}
+
+func (r *Redis) CopyCompleted(source, destination string, replace bool) rueidis.Completed {
+	if replace {
+		return r.conn.B().Copy().Source(source).Destination(destination).Replace().Build()
+	}
+	return r.conn.B().Copy().Source(source).Destination(destination).Build()
+}
+
+// Copy copies source to destination and reports whether the copy
+// was made. Without replace an existing destination is kept.
+func (r *Redis) Copy(ctx context.Context, source, destination string, replace bool) (bool, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.CopyCompleted(source, destination, replace)).AsBool()
+	r.writeTimingAndCounter(startTime, "redis_copy", err == nil)
+
+	return result, err
+}
+
+// applied converts the reply of a conditional SET: OK when the
+// write was applied and nil otherwise.
+func applied(result reply) (bool, error) {
+	err := result.Error()
+	if rueidis.IsRedisNil(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Multi) SetNXCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	return r.mainConn.SetNXCompleted(key, value, ttl)
+}
+
+func (r *Multi) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.SetNX(ctx, key, value, ttl)
+	})
+}
+
+func (r *Multi) SetXXCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	return r.mainConn.SetXXCompleted(key, value, ttl)
+}
+
+func (r *Multi) SetXX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.SetXX(ctx, key, value, ttl)
+	})
+}
+
+func (r *Multi) SetGetCompleted(key, value string, ttl time.Duration) rueidis.Completed {
+	return r.mainConn.SetGetCompleted(key, value, ttl)
+}
+
+func (r *Multi) SetGet(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.SetGet(ctx, key, value, ttl)
+	})
+}
+
+func (r *Multi) SetKeepTTLCompleted(key, value string) rueidis.Completed {
+	return r.mainConn.SetKeepTTLCompleted(key, value)
+}
+
+func (r *Multi) SetKeepTTL(ctx context.Context, key, value string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.SetKeepTTL(ctx, key, value)
+	})
+}
+
+func (r *Multi) GetExCompleted(key string, ttl time.Duration) rueidis.Completed {
+	return r.mainConn.GetExCompleted(key, ttl)
+}
+
+func (r *Multi) GetEx(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.GetEx(ctx, key, ttl)
+	})
+}
+
+func (r *Multi) GetExPersistCompleted(key string) rueidis.Completed {
+	return r.mainConn.GetExPersistCompleted(key)
+}
+
+func (r *Multi) GetExPersist(ctx context.Context, key string) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.GetExPersist(ctx, key)
+	})
+}
+
+func (r *Multi) GetDelCompleted(key string) rueidis.Completed {
+	return r.mainConn.GetDelCompleted(key)
+}
+
+func (r *Multi) GetDel(ctx context.Context, key string) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.GetDel(ctx, key)
+	})
+}
+
+func (r *Multi) CopyCompleted(source, destination string, replace bool) rueidis.Completed {
+	return r.mainConn.CopyCompleted(source, destination, replace)
+}
+
+func (r *Multi) Copy(ctx context.Context, source, destination string, replace bool) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.Copy(ctx, source, destination, replace)
+	})
+}
@@ -0,0 +1,454 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultMaxAttempts     = 5
+	_defaultJobBackoff      = time.Second
+	_defaultJobMaxBackoff   = 10 * time.Minute
+	_defaultJobPollInterval = time.Second
+)
+
+var (
+	ErrEmptyJobID   = errors.New("job id is empty")
+	ErrJobLeaseLost = errors.New("job lease is lost")
+)
+
+// _scheduleScript adds a job unless a job with the same ID is
+// scheduled, running or dead.
+var _scheduleScript = NewScript("delayed_schedule", `
+if redis.call('HSETNX', KEYS[2], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// _claimScript returns due jobs to the schedule when their lease
+// expired, then moves up to limit due jobs to the running set with
+// a lease and returns id, payload, attempt and claim token of every
+// job. Due jobs that used up max attempts go to the dead-letter set.
+var _claimScript = NewScript("delayed_claim", `
+local now = ARGV[1]
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now, 'LIMIT', 0, ARGV[3])) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('HDEL', KEYS[6], id)
+	redis.call('ZADD', KEYS[1], now, id)
+end
+
+local jobs = {}
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, ARGV[3])) do
+	redis.call('ZREM', KEYS[1], id)
+	if tonumber(redis.call('HGET', KEYS[4], id) or '0') >= tonumber(ARGV[4]) then
+		redis.call('ZADD', KEYS[5], now, id)
+	else
+		local token = redis.call('INCR', KEYS[7])
+		redis.call('HSET', KEYS[6], id, token)
+		redis.call('ZADD', KEYS[2], ARGV[2], id)
+		table.insert(jobs, id)
+		table.insert(jobs, redis.call('HGET', KEYS[3], id) or '')
+		table.insert(jobs, redis.call('HINCRBY', KEYS[4], id, 1))
+		table.insert(jobs, token)
+	end
+end
+return jobs
+`)
+
+// _extendScript moves the lease deadline of a running job held
+// with the given claim token.
+var _extendScript = NewScript("delayed_extend", `
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] or not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// _completeScript removes a finished job held with the given claim
+// token.
+var _completeScript = NewScript("delayed_complete", `
+if redis.call('HGET', KEYS[4], ARGV[1]) ~= ARGV[2] or redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+// _moveScript moves a running job held with the given claim token
+// to the schedule for a retry or to the dead-letter set.
+var _moveScript = NewScript("delayed_move", `
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] or redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+// _reviveScript moves a dead job back to the schedule with a
+// fresh attempt counter.
+var _reviveScript = NewScript("delayed_revive", `
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+return 1
+`)
+
+// _cancelScript removes a job wherever it is.
+var _cancelScript = NewScript("delayed_cancel", `
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[5], ARGV[1])
+redis.call('HDEL', KEYS[6], ARGV[1])
+return redis.call('HDEL', KEYS[4], ARGV[1])
+`)
+
+type DelayedQueueConfig struct {
+	Name              string        `yaml:"name"`
+	Workers           int           `yaml:"workers"`
+	MaxAttempts       int           `yaml:"max_attempts"`
+	Backoff           time.Duration `yaml:"backoff"`
+	MaxBackoff        time.Duration `yaml:"max_backoff"`
+	PollInterval      time.Duration `yaml:"poll_interval"`
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+}
+
+// Job is a claimed job. Attempt starts at 1, Token identifies the
+// claim: Extend, Complete and Fail of a job whose lease expired and
+// was claimed again return ErrJobLeaseLost.
+type Job struct {
+	ID      string
+	Payload string
+	Attempt int64
+	Token   int64
+}
+
+// JobHandler runs a job. A returned error schedules a retry with
+// backoff, after MaxAttempts the job goes to the dead-letter set.
+type JobHandler func(ctx context.Context, job Job) error
+
+// DelayedQueue runs jobs at a given time on any instance. Jobs
+// are kept in a sorted set by run time and claimed atomically by
+// a script, a claimed job is leased for VisibilityTimeout and
+// returns to the schedule when its worker dies. Workers extend the
+// lease while the handler runs. Job IDs are unique:
+// a job can't be scheduled while a job with the same ID is
+// scheduled, running or dead.
+//
+// DelayedQueue implements service.ServiceWithDown, Workers
+// goroutines run jobs between Up and Down.
+type DelayedQueue struct {
+	redis    *Redis
+	cfg      DelayedQueueConfig
+	handler  JobHandler
+	backoff  Policy
+	schedule string
+	running  string
+	dead     string
+	jobs     string
+	attempts string
+	tokens   string
+	counter  string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDelayedQueue(r *Redis, cfg DelayedQueueConfig, handler JobHandler) *DelayedQueue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = _defaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = _defaultJobBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = _defaultJobMaxBackoff
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _defaultJobPollInterval
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = _defaultVisibilityTimeout
+	}
+
+	prefix := "delayed:{" + cfg.Name + "}:"
+	return &DelayedQueue{
+		redis:    r,
+		cfg:      cfg,
+		handler:  handler,
+		backoff:  Policy{Backoff: cfg.Backoff, MaxBackoff: cfg.MaxBackoff},
+		schedule: prefix + "schedule",
+		running:  prefix + "running",
+		dead:     prefix + "dead",
+		jobs:     prefix + "jobs",
+		attempts: prefix + "attempts",
+		tokens:   prefix + "tokens",
+		counter:  prefix + "token",
+	}
+}
+
+// Schedule adds a job to run at the given time and reports
+// whether it was added. A duplicate ID is not an error, the
+// existing job is kept.
+func (q *DelayedQueue) Schedule(ctx context.Context, id, payload string, at time.Time) (bool, error) {
+	if id == "" {
+		return false, ErrEmptyJobID
+	}
+	return q.redis.Eval(ctx, _scheduleScript,
+		[]string{q.schedule, q.jobs},
+		[]string{id, payload, millis(at)},
+	).AsBool()
+}
+
+// ScheduleIn adds a job to run after delay.
+func (q *DelayedQueue) ScheduleIn(ctx context.Context, id, payload string, delay time.Duration) (bool, error) {
+	return q.Schedule(ctx, id, payload, time.Now().Add(delay))
+}
+
+// Cancel removes a scheduled, running or dead job and reports
+// whether it existed. A running job is not interrupted, but its
+// result is ignored.
+func (q *DelayedQueue) Cancel(ctx context.Context, id string) (bool, error) {
+	return q.redis.Eval(ctx, _cancelScript,
+		[]string{q.schedule, q.running, q.dead, q.jobs, q.attempts, q.tokens},
+		[]string{id},
+	).AsBool()
+}
+
+// Claim leases up to limit due jobs. Due jobs that used up
+// MaxAttempts, because their workers died, go to the dead-letter
+// set instead.
+func (q *DelayedQueue) Claim(ctx context.Context, limit int) ([]Job, error) {
+	now := time.Now()
+	values, err := q.redis.Eval(ctx, _claimScript,
+		[]string{q.schedule, q.running, q.jobs, q.attempts, q.dead, q.tokens, q.counter},
+		[]string{millis(now), millis(now.Add(q.cfg.VisibilityTimeout)), strconv.Itoa(limit), strconv.Itoa(q.cfg.MaxAttempts)},
+	).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(values)/4)
+	for idx := 0; idx+3 < len(values); idx += 4 {
+		var job Job
+		job.ID, _ = values[idx].ToString()
+		job.Payload, _ = values[idx+1].ToString()
+		job.Attempt, _ = values[idx+2].AsInt64()
+		job.Token, _ = values[idx+3].AsInt64()
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Extend leases a claimed job for another VisibilityTimeout.
+func (q *DelayedQueue) Extend(ctx context.Context, job Job) error {
+	return held(q.redis.Eval(ctx, _extendScript,
+		[]string{q.running, q.tokens},
+		[]string{job.ID, strconv.FormatInt(job.Token, 10), millis(time.Now().Add(q.cfg.VisibilityTimeout))},
+	))
+}
+
+// Complete removes a claimed job after it was run.
+func (q *DelayedQueue) Complete(ctx context.Context, job Job) error {
+	return held(q.redis.Eval(ctx, _completeScript,
+		[]string{q.running, q.jobs, q.attempts, q.tokens},
+		[]string{job.ID, strconv.FormatInt(job.Token, 10)},
+	))
+}
+
+// Fail schedules a retry of a claimed job with backoff or moves it
+// to the dead-letter set after MaxAttempts.
+func (q *DelayedQueue) Fail(ctx context.Context, job Job) error {
+	if job.Attempt >= int64(q.cfg.MaxAttempts) {
+		return q.move(ctx, job, q.dead, time.Now())
+	}
+	return q.move(ctx, job, q.schedule, time.Now().Add(q.backoff.backoff(int(job.Attempt-1))))
+}
+
+// Dead returns up to count jobs of the dead-letter set, oldest
+// first.
+func (q *DelayedQueue) Dead(ctx context.Context, count int64) ([]Job, error) {
+	ids, err := q.redis.ZRangeByScore(ctx, q.dead, "-inf", "+inf", 0, count)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	cmds := make(rueidis.Commands, 0, 2*len(ids))
+	for _, id := range ids {
+		member, _ := id.ToString()
+		cmds = append(cmds,
+			q.redis.conn.B().Hget().Key(q.jobs).Field(member).Build(),
+			q.redis.conn.B().Hget().Key(q.attempts).Field(member).Build(),
+		)
+	}
+	results := q.redis.DoMulti(ctx, cmds...)
+
+	jobs := make([]Job, len(ids))
+	for idx, id := range ids {
+		jobs[idx].ID, _ = id.ToString()
+		jobs[idx].Payload, _ = results[2*idx].ToString()
+		jobs[idx].Attempt, _ = results[2*idx+1].AsInt64()
+	}
+	return jobs, nil
+}
+
+// Revive moves a dead job back to the schedule with a fresh
+// attempt counter.
+func (q *DelayedQueue) Revive(ctx context.Context, id string) (bool, error) {
+	return q.redis.Eval(ctx, _reviveScript,
+		[]string{q.dead, q.schedule, q.attempts},
+		[]string{id, millis(time.Now())},
+	).AsBool()
+}
+
+func (q *DelayedQueue) Up(ctx context.Context) error {
+	q.mu.Lock()
+	ctx, q.cancel = context.WithCancel(ctx)
+	q.done = make(chan struct{})
+	q.mu.Unlock()
+	defer close(q.done)
+
+	var wg sync.WaitGroup
+	for range q.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (q *DelayedQueue) Down(ctx context.Context) error {
+	q.mu.Lock()
+	cancel, done := q.cancel, q.done
+	q.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *DelayedQueue) work(ctx context.Context) {
+	for {
+		jobs, err := q.Claim(ctx, 1)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Ошибка получения отложенных задач", "error", err, "queue", q.cfg.Name)
+		}
+		if len(jobs) == 0 {
+			if !sleep(ctx, q.cfg.PollInterval) {
+				return
+			}
+			continue
+		}
+
+		for _, job := range jobs {
+			q.run(ctx, job)
+		}
+	}
+}
+
+func (q *DelayedQueue) run(ctx context.Context, job Job) {
+	// The result of a started job is saved even on shutdown.
+	saveCtx := context.WithoutCancel(ctx)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	extended := make(chan struct{})
+	go func() {
+		defer close(extended)
+		q.extend(jobCtx, cancel, job)
+	}()
+
+	err := q.handler(jobCtx, job)
+	cancel()
+	<-extended
+
+	if err == nil {
+		err = q.Complete(saveCtx, job)
+	} else {
+		slog.Error("Ошибка выполнения отложенной задачи", "error", err, "queue", q.cfg.Name, "id", job.ID, "attempt", job.Attempt)
+		err = q.Fail(saveCtx, job)
+	}
+	if errors.Is(err, ErrJobLeaseLost) {
+		slog.Warn("Результат отложенной задачи не сохранен, аренда потеряна", "queue", q.cfg.Name, "id", job.ID)
+	} else if err != nil {
+		slog.Error("Ошибка сохранения результата отложенной задачи", "error", err, "queue", q.cfg.Name, "id", job.ID)
+	}
+}
+
+// extend renews the lease of job every half of VisibilityTimeout
+// until ctx is done and cancels the handler when the lease is lost.
+func (q *DelayedQueue) extend(ctx context.Context, cancel context.CancelFunc, job Job) {
+	ticker := time.NewTicker(q.cfg.VisibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := q.Extend(ctx, job)
+		if errors.Is(err, ErrJobLeaseLost) {
+			slog.Warn("Аренда отложенной задачи потеряна", "queue", q.cfg.Name, "id", job.ID)
+			cancel()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Ошибка продления аренды отложенной задачи", "error", err, "queue", q.cfg.Name, "id", job.ID)
+		}
+	}
+}
+
+func (q *DelayedQueue) move(ctx context.Context, job Job, destination string, at time.Time) error {
+	return held(q.redis.Eval(ctx, _moveScript,
+		[]string{q.running, destination, q.tokens},
+		[]string{job.ID, strconv.FormatInt(job.Token, 10), millis(at)},
+	))
+}
+
+// held returns ErrJobLeaseLost when a script didn't find the job
+// held with its claim token.
+func held(result rueidis.RedisResult) error {
+	ok, err := result.AsBool()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+func millis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
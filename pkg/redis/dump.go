@@ -0,0 +1,439 @@
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultExportScanCount = 1000
+	_exportBatch            = 100
+	_encodingBase64         = "base64"
+)
+
+type ImportMode string
+
+const (
+	// ImportOverwrite replaces existing keys.
+	ImportOverwrite ImportMode = "overwrite"
+	// ImportSkip keeps existing keys, also the ones written while
+	// the record is imported.
+	ImportSkip ImportMode = "skip"
+)
+
+type ExportOptions struct {
+	Match     string
+	ScanCount int64
+	Gzip      bool
+}
+
+type ImportOptions struct {
+	Mode ImportMode
+}
+
+type ImportStats struct {
+	Imported int
+	Skipped  int
+}
+
+// DumpRecord is a line of an export. Value is a string, a map of
+// fields, a list of elements or members, or a list of ZMember for
+// sorted sets. With Encoding "base64" Key and all strings of Value
+// are base64, it is used for keys and values that aren't valid
+// UTF-8. TTL is in
+// milliseconds, 0 for keys without TTL.
+type DumpRecord struct {
+	Key      string          `json:"key"`
+	Type     string          `json:"type"`
+	TTL      int64           `json:"ttl_ms,omitempty"`
+	Encoding string          `json:"encoding,omitempty"`
+	Value    json.RawMessage `json:"value"`
+}
+
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// Export writes the string, hash, list, set and sorted set keys
+// matching the pattern to w as NDJSON sorted by key, gzipped with
+// Gzip. Keys of other types are skipped. It returns the number of
+// exported keys.
+func (r *Redis) Export(ctx context.Context, w io.Writer, opts ExportOptions) (int, error) {
+	if opts.Match == "" {
+		opts.Match = "*"
+	}
+	if opts.ScanCount <= 0 {
+		opts.ScanCount = _defaultExportScanCount
+	}
+
+	found, err := r.ScanAllKeys(ctx, opts.Match, opts.ScanCount)
+	if err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var gz *gzip.Writer
+	if opts.Gzip {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	exported := 0
+	for batch := range slices.Chunk(keys, _exportBatch) {
+		records, err := r.dumpRecords(ctx, batch)
+		if err != nil {
+			return exported, err
+		}
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+	}
+
+	if err := buf.Flush(); err != nil {
+		return exported, err
+	}
+	if gz != nil {
+		return exported, gz.Close()
+	}
+	return exported, nil
+}
+
+// Import loads an export written by Export, gzipped or not, and
+// returns the number of imported and skipped keys. Every record is
+// written in MULTI/EXEC, so a key is never seen half-restored.
+func (r *Redis) Import(ctx context.Context, reader io.Reader, opts ImportOptions) (ImportStats, error) {
+	var stats ImportStats
+	if opts.Mode == "" {
+		opts.Mode = ImportOverwrite
+	}
+
+	buf := bufio.NewReader(reader)
+	if magic, _ := buf.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buf)
+		if err != nil {
+			return stats, err
+		}
+		defer gz.Close()
+		buf = bufio.NewReader(gz)
+	}
+
+	dec := json.NewDecoder(buf)
+	for line := 1; ; line++ {
+		var record DumpRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			return stats, nil
+		} else if err != nil {
+			return stats, fmt.Errorf("Ошибка чтения записи %d: %w", line, err)
+		}
+
+		key, cmds, err := r.restoreCommands(record)
+		if err != nil {
+			return stats, fmt.Errorf("Ошибка разбора записи %d: %w", line, err)
+		}
+		imported, err := r.restore(ctx, key, cmds, opts.Mode == ImportSkip)
+		if err != nil {
+			return stats, fmt.Errorf("Ошибка записи ключа %s: %w", record.Key, err)
+		}
+		if imported {
+			stats.Imported++
+		} else {
+			stats.Skipped++
+		}
+	}
+}
+
+// restore runs cmds in MULTI/EXEC on a dedicated connection. With
+// skip the key is watched and the transaction isn't run when the
+// key exists or is aborted when it is written meanwhile, restore
+// then reports false.
+func (r *Redis) restore(ctx context.Context, key string, cmds rueidis.Commands, skip bool) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var imported bool
+	err := r.conn.Dedicated(func(client rueidis.DedicatedClient) error {
+		b := client.B()
+		if skip {
+			if err := client.Do(ctx, b.Watch().Key(key).Build()).Error(); err != nil {
+				return err
+			}
+			exists, err := client.Do(ctx, b.Exists().Key(key).Build()).AsBool()
+			if err != nil || exists {
+				client.Do(ctx, b.Unwatch().Build())
+				return err
+			}
+		}
+
+		tx := make(rueidis.Commands, 0, len(cmds)+2)
+		tx = append(tx, b.Multi().Build())
+		tx = append(tx, cmds...)
+		tx = append(tx, b.Exec().Build())
+		results := client.DoMulti(ctx, tx...)
+
+		replies, err := results[len(results)-1].ToArray()
+		if rueidis.IsRedisNil(err) {
+			// The watched key was written meanwhile.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if err := reply.Error(); err != nil {
+				return err
+			}
+		}
+		imported = true
+		return nil
+	})
+	return imported, err
+}
+
+// dumpRecords reads the type, TTL and value of keys. Keys that
+// were deleted meanwhile, are empty or have unsupported types are
+// skipped.
+func (r *Redis) dumpRecords(ctx context.Context, keys []string) ([]DumpRecord, error) {
+	b := r.conn.B()
+	cmds := make(rueidis.Commands, 0, 2*len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, b.Type().Key(key).Build(), b.Pttl().Key(key).Build())
+	}
+	results := r.DoMulti(ctx, cmds...)
+	if err := HasError(results); err != nil {
+		return nil, err
+	}
+
+	records := make([]DumpRecord, 0, len(keys))
+	cmds = cmds[:0]
+	for idx, key := range keys {
+		keyType, _ := results[2*idx].ToString()
+		ttl, _ := results[2*idx+1].AsInt64()
+
+		var cmd rueidis.Completed
+		switch keyType {
+		case "string":
+			cmd = b.Get().Key(key).Build()
+		case "hash":
+			cmd = b.Hgetall().Key(key).Build()
+		case "list":
+			cmd = b.Lrange().Key(key).Start(0).Stop(-1).Build()
+		case "set":
+			cmd = b.Smembers().Key(key).Build()
+		case "zset":
+			cmd = b.Zrange().Key(key).Min("0").Max("-1").Withscores().Build()
+		default:
+			continue
+		}
+		cmds = append(cmds, cmd)
+		records = append(records, DumpRecord{Key: key, Type: keyType, TTL: max(ttl, 0)})
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	results = r.DoMulti(ctx, cmds...)
+	dumped := records[:0]
+	for idx, record := range records {
+		found, err := dumpValue(&record, results[idx])
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения ключа %s: %w", record.Key, err)
+		}
+		if found {
+			dumped = append(dumped, record)
+		}
+	}
+	return dumped, nil
+}
+
+// dumpValue sets the value of record from result and encodes Key
+// and Value when one of them isn't valid UTF-8. It reports false
+// for a deleted or empty key.
+func dumpValue(record *DumpRecord, result rueidis.RedisResult) (bool, error) {
+	var (
+		value any
+		key   = record.Key
+		strs  = []*string{&key}
+	)
+	switch record.Type {
+	case "string":
+		s, err := result.ToString()
+		if rueidis.IsRedisNil(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		value, strs = &s, append(strs, &s)
+	case "hash":
+		fields, err := result.AsStrMap()
+		if err != nil || len(fields) == 0 {
+			return false, err
+		}
+		pairs := make([][2]string, 0, len(fields))
+		for k, v := range fields {
+			pairs = append(pairs, [2]string{k, v})
+		}
+		for idx := range pairs {
+			strs = append(strs, &pairs[idx][0], &pairs[idx][1])
+		}
+		value = pairs
+	case "zset":
+		scores, err := result.AsZScores()
+		if err != nil || len(scores) == 0 {
+			return false, err
+		}
+		members := make([]ZMember, len(scores))
+		for idx, score := range scores {
+			members[idx] = ZMember{Member: score.Member, Score: score.Score}
+			strs = append(strs, &members[idx].Member)
+		}
+		value = members
+	default:
+		elements, err := result.AsStrSlice()
+		if err != nil || len(elements) == 0 {
+			return false, err
+		}
+		for idx := range elements {
+			strs = append(strs, &elements[idx])
+		}
+		value = elements
+	}
+
+	encoding := ""
+	if slices.ContainsFunc(strs, func(s *string) bool { return !utf8.ValidString(*s) }) {
+		encoding = _encodingBase64
+		for _, s := range strs {
+			*s = base64.StdEncoding.EncodeToString([]byte(*s))
+		}
+	}
+
+	if pairs, ok := value.([][2]string); ok {
+		fields := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			fields[pair[0]] = pair[1]
+		}
+		value = fields
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	record.Key, record.Value, record.Encoding = key, raw, encoding
+	return true, nil
+}
+
+// restoreCommands returns the decoded key of record and the
+// commands that write it: DEL, the write and PEXPIRE.
+func (r *Redis) restoreCommands(record DumpRecord) (string, rueidis.Commands, error) {
+	decode := func(s string) (string, error) {
+		if record.Encoding != _encodingBase64 {
+			return s, nil
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		return string(raw), err
+	}
+
+	key, err := decode(record.Key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b := r.conn.B()
+	cmds := rueidis.Commands{b.Del().Key(key).Build()}
+	switch record.Type {
+	case "string":
+		var value string
+		if err := json.Unmarshal(record.Value, &value); err != nil {
+			return "", nil, err
+		}
+		value, err := decode(value)
+		if err != nil {
+			return "", nil, err
+		}
+		cmds = append(cmds, b.Set().Key(key).Value(value).Build())
+	case "hash":
+		var fields map[string]string
+		if err := json.Unmarshal(record.Value, &fields); err != nil {
+			return "", nil, err
+		}
+		if len(fields) == 0 {
+			return "", nil, fmt.Errorf("пустой hash %s", record.Key)
+		}
+		cmd := b.Hset().Key(key).FieldValue()
+		for k, v := range fields {
+			field, err := decode(k)
+			if err != nil {
+				return "", nil, err
+			}
+			if v, err = decode(v); err != nil {
+				return "", nil, err
+			}
+			cmd = cmd.FieldValue(field, v)
+		}
+		cmds = append(cmds, cmd.Build())
+	case "list", "set":
+		var elements []string
+		if err := json.Unmarshal(record.Value, &elements); err != nil {
+			return "", nil, err
+		}
+		if len(elements) == 0 {
+			return "", nil, fmt.Errorf("пустой %s %s", record.Type, record.Key)
+		}
+		for idx := range elements {
+			var err error
+			if elements[idx], err = decode(elements[idx]); err != nil {
+				return "", nil, err
+			}
+		}
+		if record.Type == "list" {
+			cmds = append(cmds, b.Rpush().Key(key).Element(elements...).Build())
+		} else {
+			cmds = append(cmds, b.Sadd().Key(key).Member(elements...).Build())
+		}
+	case "zset":
+		var members []ZMember
+		if err := json.Unmarshal(record.Value, &members); err != nil {
+			return "", nil, err
+		}
+		if len(members) == 0 {
+			return "", nil, fmt.Errorf("пустой zset %s", record.Key)
+		}
+		cmd := b.Zadd().Key(key).ScoreMember()
+		for _, member := range members {
+			name, err := decode(member.Member)
+			if err != nil {
+				return "", nil, err
+			}
+			cmd = cmd.ScoreMember(member.Score, name)
+		}
+		cmds = append(cmds, cmd.Build())
+	default:
+		return "", nil, fmt.Errorf("неизвестный тип %q ключа %s", record.Type, record.Key)
+	}
+
+	if record.TTL > 0 {
+		cmds = append(cmds, b.Pexpire().Key(key).Milliseconds(record.TTL).Build())
+	}
+	return key, cmds, nil
+}
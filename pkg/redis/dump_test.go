@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDumpRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   DumpRecord
+		reply    string
+		found    bool
+		encoding string
+		want     [][]string
+	}{
+		{
+			name:     "binary string with ttl",
+			record:   DumpRecord{Key: "bin\xff", Type: "string", TTL: 1500},
+			reply:    "$2\r\n\x00\xff\r\n",
+			found:    true,
+			encoding: _encodingBase64,
+			want: [][]string{
+				{"DEL", "bin\xff"},
+				{"SET", "bin\xff", "\x00\xff"},
+				{"PEXPIRE", "bin\xff", "1500"},
+			},
+		},
+		{
+			name:     "binary key",
+			record:   DumpRecord{Key: "\xfe", Type: "string"},
+			reply:    "$5\r\nvalue\r\n",
+			found:    true,
+			encoding: _encodingBase64,
+			want:     [][]string{{"DEL", "\xfe"}, {"SET", "\xfe", "value"}},
+		},
+		{
+			name:   "hash",
+			record: DumpRecord{Key: "h", Type: "hash"},
+			reply:  "%1\r\n$1\r\nf\r\n$1\r\nv\r\n",
+			found:  true,
+			want:   [][]string{{"DEL", "h"}, {"HSET", "h", "f", "v"}},
+		},
+		{
+			name:   "list",
+			record: DumpRecord{Key: "l", Type: "list", TTL: 10},
+			reply:  "*2\r\n$1\r\nx\r\n$1\r\ny\r\n",
+			found:  true,
+			want:   [][]string{{"DEL", "l"}, {"RPUSH", "l", "x", "y"}, {"PEXPIRE", "l", "10"}},
+		},
+		{
+			name:   "set",
+			record: DumpRecord{Key: "s", Type: "set"},
+			reply:  "~1\r\n$1\r\nm\r\n",
+			found:  true,
+			want:   [][]string{{"DEL", "s"}, {"SADD", "s", "m"}},
+		},
+		{
+			name:   "zset",
+			record: DumpRecord{Key: "z", Type: "zset"},
+			reply:  "*2\r\n*2\r\n$1\r\na\r\n,1.5\r\n*2\r\n$1\r\nb\r\n,-2\r\n",
+			found:  true,
+			want:   [][]string{{"DEL", "z"}, {"ZADD", "z", "1.5", "a", "-2", "b"}},
+		},
+		{
+			name:     "binary zset member",
+			record:   DumpRecord{Key: "z", Type: "zset"},
+			reply:    "*1\r\n*2\r\n$1\r\n\xff\r\n,3\r\n",
+			found:    true,
+			encoding: _encodingBase64,
+			want:     [][]string{{"DEL", "z"}, {"ZADD", "z", "3", "\xff"}},
+		},
+		{
+			name:   "deleted string",
+			record: DumpRecord{Key: "gone", Type: "string"},
+			reply:  "_\r\n",
+		},
+		{
+			name:   "empty hash",
+			record: DumpRecord{Key: "h", Type: "hash"},
+			reply:  "%0\r\n",
+		},
+		{
+			name:   "empty set",
+			record: DumpRecord{Key: "s", Type: "set"},
+			reply:  "~0\r\n",
+		},
+		{
+			name:   "empty zset",
+			record: DumpRecord{Key: "z", Type: "zset"},
+			reply:  "*0\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient(t, func([]string) string { return tt.reply })
+			r := &Redis{conn: client}
+			result := client.Do(context.Background(), client.B().Get().Key("key").Build())
+
+			record := tt.record
+			found, err := dumpValue(&record, result)
+			if err != nil {
+				t.Fatalf("dumpValue: %v", err)
+			}
+			if found != tt.found {
+				t.Fatalf("dumpValue found = %v, want %v", found, tt.found)
+			}
+			if !found {
+				return
+			}
+			if record.Encoding != tt.encoding {
+				t.Errorf("encoding = %q, want %q", record.Encoding, tt.encoding)
+			}
+
+			line, err := json.Marshal(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded DumpRecord
+			if err := json.Unmarshal(line, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			key, cmds, err := r.restoreCommands(decoded)
+			if err != nil {
+				t.Fatalf("restoreCommands: %v", err)
+			}
+			if key != tt.record.Key {
+				t.Errorf("key = %q, want %q", key, tt.record.Key)
+			}
+			got := make([][]string, len(cmds))
+			for idx, cmd := range cmds {
+				got[idx] = cmd.Commands()
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("commands = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const _defaultDurabilityTimeout = time.Second
+
+// Key slot flags of rueidis commands: keyless commands of a cluster
+// client have _anySlot, commands of other clients have _noSlot set.
+const (
+	_anySlot = uint16(1 << 14)
+	_noSlot  = uint16(1 << 15)
+)
+
+var ErrUnderReplicated = errors.New("write is under-replicated")
+
+// _keylessCommands are not read-only for rueidis but have no keys,
+// they don't need a durability.
+var _keylessCommands = map[string]struct{}{
+	"AUTH": {}, "CLIENT": {}, "CLUSTER": {}, "COMMAND": {}, "CONFIG": {},
+	"DBSIZE": {}, "ECHO": {}, "FUNCTION": {}, "HELLO": {}, "INFO": {},
+	"LATENCY": {}, "MEMORY": {}, "PING": {}, "PUBLISH": {}, "SCRIPT": {},
+	"SELECT": {}, "SLOWLOG": {}, "SPUBLISH": {}, "TIME": {}, "WAIT": {},
+	"WAITAOF": {},
+}
+
+// Durability is the number of replicas that must acknowledge a
+// write within Timeout. With Local the write must also be fsynced
+// to the AOF of Local servers (WAITAOF, redis 7.2 or newer).
+type Durability struct {
+	Replicas int64         `yaml:"replicas"`
+	Local    int64         `yaml:"local"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// DurabilityRule sets the durability of writes to keys matching
+// Pattern (glob with * and ?). Durability applies to write
+// commands, DoMultiExec and Batch.Exec, other pipelines can be sent
+// with DoDurable.
+type DurabilityRule struct {
+	Pattern  string        `yaml:"pattern"`
+	Replicas int64         `yaml:"replicas"`
+	Local    int64         `yaml:"local"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+type durabilityKey struct{}
+
+// WithDurability sets the durability of the writes sent with ctx,
+// it overrides the durability rules.
+func WithDurability(ctx context.Context, d Durability) context.Context {
+	return context.WithValue(ctx, durabilityKey{}, d)
+}
+
+func (d Durability) withDefaults() Durability {
+	if d.Timeout <= 0 {
+		d.Timeout = _defaultDurabilityTimeout
+	}
+	return d
+}
+
+func (d Durability) completed(b rueidis.Builder) rueidis.Completed {
+	if d.Local > 0 {
+		return b.Waitaof().Numlocal(d.Local).Numreplicas(d.Replicas).Timeout(d.Timeout.Milliseconds()).Build()
+	}
+	return b.Wait().Numreplicas(d.Replicas).Timeout(d.Timeout.Milliseconds()).Build()
+}
+
+// check returns ErrUnderReplicated when the reply of WAIT or
+// WAITAOF is below the target.
+func (d Durability) check(result rueidis.RedisResult) error {
+	var local, replicas int64
+	if d.Local > 0 {
+		counts, err := result.AsIntSlice()
+		if err != nil {
+			return err
+		}
+		if len(counts) != 2 {
+			return fmt.Errorf("Ошибка разбора ответа WAITAOF: %v", counts)
+		}
+		local, replicas = counts[0], counts[1]
+	} else {
+		var err error
+		if replicas, err = result.AsInt64(); err != nil {
+			return err
+		}
+	}
+
+	if replicas < d.Replicas || local < d.Local {
+		return fmt.Errorf("%w: подтвердили %d из %d реплик, %d из %d локально",
+			ErrUnderReplicated, replicas, d.Replicas, local, d.Local)
+	}
+	return nil
+}
+
+// durability returns the durability of the writes in multi: the
+// one of ctx or the strictest of the rules matching their keys.
+// Read-only and keyless commands don't need one.
+func (r *Redis) durability(ctx context.Context, multi rueidis.Commands) (Durability, bool) {
+	writes := slices.ContainsFunc(multi, func(cmd rueidis.Completed) bool {
+		return cmd.IsWrite() && len(commandKeys(cmd.Commands())) > 0
+	})
+	if !writes {
+		return Durability{}, false
+	}
+	if d, ok := ctx.Value(durabilityKey{}).(Durability); ok {
+		return d.withDefaults(), true
+	}
+	if len(r.durabilityRules) == 0 {
+		return Durability{}, false
+	}
+
+	var (
+		result Durability
+		found  bool
+	)
+	for _, cmd := range multi {
+		if cmd.IsReadOnly() {
+			continue
+		}
+		for _, key := range commandKeys(cmd.Commands()) {
+			for _, rule := range r.durabilityRules {
+				if !matchPattern(rule.Pattern, key) {
+					continue
+				}
+				result.Replicas = max(result.Replicas, rule.Replicas)
+				result.Local = max(result.Local, rule.Local)
+				result.Timeout = max(result.Timeout, rule.Timeout)
+				found = true
+				break
+			}
+		}
+	}
+	return result.withDefaults(), found
+}
+
+// commandKeys returns the keys of a write command: the declared
+// keys of scripts and functions, every key of DEL, UNLINK, TOUCH,
+// MSET and MSETNX, none of keyless commands and the first argument
+// otherwise.
+func commandKeys(commands []string) []string {
+	if len(commands) < 2 {
+		return nil
+	}
+	name := strings.ToUpper(commands[0])
+	if _, ok := _keylessCommands[name]; ok {
+		return nil
+	}
+	switch name {
+	case "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO":
+		if len(commands) < 3 {
+			return nil
+		}
+		n, err := strconv.Atoi(commands[2])
+		if err != nil || n < 0 || 3+n > len(commands) {
+			return nil
+		}
+		return commands[3 : 3+n]
+	case "DEL", "UNLINK", "TOUCH":
+		return commands[1:]
+	case "MSET", "MSETNX":
+		keys := make([]string, 0, len(commands)/2)
+		for idx := 1; idx < len(commands); idx += 2 {
+			keys = append(keys, commands[idx])
+		}
+		return keys
+	default:
+		return commands[1:2]
+	}
+}
+
+// DoDurable sends multi followed by WAIT, or WAITAOF with Local,
+// on one connection. On a cluster the commands are grouped by key
+// slot and every group is followed by WAIT on its own node. The error is ErrUnderReplicated when the
+// target isn't reached, the writes are applied on the primary
+// anyway. Errors of the commands are in their results.
+func (r *Redis) DoDurable(ctx context.Context, d Durability, multi ...rueidis.Completed) ([]rueidis.RedisResult, error) {
+	return r.doDurable(ctx, r.conn, d, multi)
+}
+
+// doDurableOn sends a single write with WAIT under the policy
+// timeout, extended by the timeout of WAIT.
+func (r *Redis) doDurableOn(ctx context.Context, client rueidis.Client, d Durability, cmd rueidis.Completed, timeout time.Duration) reply {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout+d.Timeout)
+		defer cancel()
+	}
+	results, err := r.doDurable(ctx, client, d, rueidis.Commands{cmd})
+	if len(results) == 0 {
+		return reply{err: err}
+	}
+	return reply{result: results[0], err: err}
+}
+
+func (r *Redis) doDurable(ctx context.Context, conn rueidis.Client, d Durability, multi rueidis.Commands) ([]rueidis.RedisResult, error) {
+	d = d.withDefaults()
+	groups := slotGroups(multi)
+	results := make([]rueidis.RedisResult, len(multi))
+	errs := make([]error, len(groups))
+
+	startTime := time.Now()
+	r.addInFlight(len(multi) + len(groups))
+	wg := sync.WaitGroup{}
+	for idx, group := range groups {
+		wg.Add(1)
+		go func(idx int, group []int) {
+			defer wg.Done()
+			errs[idx] = r.doDurableGroup(ctx, conn, d, multi, group, results)
+		}(idx, group)
+	}
+	wg.Wait()
+	r.addInFlight(-len(multi) - len(groups))
+	r.observePipeline(len(multi) + len(groups))
+
+	err := errors.Join(errs...)
+	r.writeTimingAndCounter(startTime, "redis_wait", err == nil)
+	return results, err
+}
+
+// doDurableGroup sends the commands of multi at indexes followed
+// by WAIT on one dedicated connection and stores their results.
+func (r *Redis) doDurableGroup(ctx context.Context, conn rueidis.Client, d Durability, multi rueidis.Commands, indexes []int, results []rueidis.RedisResult) error {
+	cmds := make(rueidis.Commands, 0, len(indexes)+1)
+	for _, idx := range indexes {
+		cmds = append(cmds, multi[idx])
+	}
+	cmds = append(cmds, d.completed(conn.B()))
+	scripts := captureEvalshas(cmds)
+
+	var resp []rueidis.RedisResult
+	err := conn.Dedicated(func(client rueidis.DedicatedClient) error {
+		resp = client.DoMulti(ctx, cmds...)
+		// Scripts resent as EVAL are followed by WAIT of their own.
+		if resent, evals := scripts.noScript(client.B(), resp); len(evals) > 0 {
+			again := client.DoMulti(ctx, append(evals, d.completed(client.B()))...)
+			for i, idx := range resent {
+				resp[idx] = again[i]
+			}
+			resp[len(indexes)] = again[len(evals)]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, idx := range indexes {
+		results[idx] = resp[i]
+	}
+	return d.check(resp[len(indexes)])
+}
+
+// slotGroups returns the indexes of multi grouped by key slot, a
+// dedicated connection of a cluster client can't serve several
+// slots. Keyless commands join the first group and every command
+// of a single node client is in one group.
+func slotGroups(multi rueidis.Commands) [][]int {
+	var (
+		groups  [][]int
+		keyless []int
+		bySlot  = make(map[uint16]int)
+	)
+	for idx, cmd := range multi {
+		slot := cmd.Slot()
+		if slot&_noSlot != 0 {
+			slot = _noSlot
+		}
+		if slot == _anySlot {
+			keyless = append(keyless, idx)
+			continue
+		}
+		group, ok := bySlot[slot]
+		if !ok {
+			group = len(groups)
+			bySlot[slot] = group
+			groups = append(groups, nil)
+		}
+		groups[group] = append(groups[group], idx)
+	}
+	if len(groups) == 0 {
+		return [][]int{keyless}
+	}
+	groups[0] = append(groups[0], keyless...)
+	slices.Sort(groups[0])
+	return groups
+}
+
+// pipeline sends multi, with WAIT when the durability of ctx or
+// of the rules applies.
+func (r *Redis) pipeline(ctx context.Context, multi rueidis.Commands) ([]rueidis.RedisResult, error) {
+	if d, ok := r.durability(ctx, multi); ok {
+		return r.DoDurable(ctx, d, multi...)
+	}
+	return r.DoMulti(ctx, multi...), nil
+}
+
+// DoDurable sends multi with WAIT to every connection, the
+// durability is checked on each of them.
+func (r *Multi) DoDurable(ctx context.Context, d Durability, multi ...rueidis.Completed) ([]rueidis.RedisResult, error) {
+	for idx := len(r.conn) - 1; idx > 0; idx-- {
+		results, err := r.conn[idx].DoDurable(ctx, d, rebuild(r.conn[idx], multi)...)
+		if err == nil {
+			err = HasError(results)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.mainConn.DoDurable(ctx, d, multi...)
+}
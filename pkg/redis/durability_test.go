@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCommandKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		want     []string
+	}{
+		{"set", []string{"SET", "a", "1"}, []string{"a"}},
+		{"lowercase", []string{"set", "a", "1"}, []string{"a"}},
+		{"no arguments", []string{"SET"}, nil},
+		{"keyless", []string{"PUBLISH", "chan", "msg"}, nil},
+		{"del", []string{"DEL", "a", "b"}, []string{"a", "b"}},
+		{"unlink", []string{"UNLINK", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{"mset", []string{"MSET", "a", "1", "b", "2"}, []string{"a", "b"}},
+		{"msetnx", []string{"MSETNX", "a", "1"}, []string{"a"}},
+		{"eval", []string{"EVAL", "return 1", "2", "a", "b", "x"}, []string{"a", "b"}},
+		{"evalsha no keys", []string{"EVALSHA", "sha", "0", "x"}, []string{}},
+		{"fcall", []string{"FCALL", "fn", "1", "a", "x"}, []string{"a"}},
+		{"eval bad numkeys", []string{"EVAL", "return 1", "x", "a"}, nil},
+		{"eval negative numkeys", []string{"EVAL", "return 1", "-1", "a"}, nil},
+		{"eval numkeys over args", []string{"EVAL", "return 1", "3", "a"}, nil},
+		{"eval without numkeys", []string{"EVAL", "return 1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandKeys(tt.commands); !slices.Equal(got, tt.want) {
+				t.Errorf("commandKeys(%q) = %q, want %q", tt.commands, got, tt.want)
+			}
+		})
+	}
+}
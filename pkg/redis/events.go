@@ -0,0 +1,307 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultEventBuffer     = 1024
+	_defaultResubscribeWait = time.Second
+	_notifyKeyspaceEvents   = "notify-keyspace-events"
+)
+
+type EventType string
+
+// Keyevent notifications consumed by EventBus.
+const (
+	EventExpired EventType = "expired"
+	EventEvicted EventType = "evicted"
+	EventDel     EventType = "del"
+)
+
+// _eventFlags are the notify-keyspace-events flags that enable
+// each event type.
+var _eventFlags = map[EventType]string{
+	EventExpired: "x",
+	EventEvicted: "e",
+	EventDel:     "g",
+}
+
+type EventBusConfig struct {
+	Events             []EventType `yaml:"events"`
+	EnableNotification bool        `yaml:"enable_notification"`
+	Buffer             int         `yaml:"buffer"`
+}
+
+// Event is a keyevent notification about a key.
+type Event struct {
+	Type EventType
+	Key  string
+	DB   int
+	Time time.Time
+}
+
+type EventHandler func(ctx context.Context, event Event)
+
+type route struct {
+	pattern string
+	types   []EventType
+	handler EventHandler
+}
+
+// EventBus consumes keyevent notifications of every node and
+// routes them to handlers by key pattern. EventBus implements
+// service.ServiceWithDown.
+type EventBus struct {
+	redis  *Redis
+	cfg    EventBusConfig
+	events chan Event
+
+	mu     sync.RWMutex
+	routes []route
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewEventBus(r *Redis, cfg EventBusConfig) *EventBus {
+	if len(cfg.Events) == 0 {
+		cfg.Events = []EventType{EventExpired, EventEvicted, EventDel}
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = _defaultEventBuffer
+	}
+	return &EventBus{
+		redis:  r,
+		cfg:    cfg,
+		events: make(chan Event, cfg.Buffer),
+	}
+}
+
+// Handle routes events about keys matching the glob pattern to
+// handler. When types are given, only events of those types are
+// routed. Every matching handler receives the event.
+func (b *EventBus) Handle(pattern string, handler EventHandler, types ...EventType) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.routes = append(b.routes, route{
+		pattern: pattern,
+		types:   types,
+		handler: handler,
+	})
+}
+
+func (b *EventBus) Up(ctx context.Context) error {
+	b.mu.Lock()
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.done = make(chan struct{})
+	b.mu.Unlock()
+	defer close(b.done)
+
+	if b.cfg.EnableNotification {
+		if err := b.enableNotification(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Handlers run on the dispatch goroutine, so waiting for it
+	// also waits for the running handler.
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.dispatch(ctx)
+	}()
+
+	for _, node := range b.redis.conn.Nodes() {
+		wg.Add(1)
+		go func(node rueidis.Client) {
+			defer wg.Done()
+			b.receive(ctx, node)
+		}(node)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (b *EventBus) Down(ctx context.Context) error {
+	b.mu.RLock()
+	cancel, done := b.cancel, b.done
+	b.mu.RUnlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enableNotification adds the flags of the configured events to
+// notify-keyspace-events of every node, keeping the flags that
+// are already set.
+func (b *EventBus) enableNotification(ctx context.Context) error {
+	builder := b.redis.conn.B()
+	for addr, node := range b.redis.conn.Nodes() {
+		current, err := node.Do(ctx, builder.ConfigGet().Parameter(_notifyKeyspaceEvents).Build()).AsStrMap()
+		if err != nil {
+			return fmt.Errorf("Ошибка чтения %s на %s: %w", _notifyKeyspaceEvents, addr, err)
+		}
+
+		flags := current[_notifyKeyspaceEvents]
+		required := "E"
+		for _, eventType := range b.cfg.Events {
+			required += _eventFlags[eventType]
+		}
+		for _, flag := range required {
+			// "A" is an alias for all key event classes except "E".
+			if strings.ContainsRune(flags, flag) || (flag != 'E' && strings.ContainsRune(flags, 'A')) {
+				continue
+			}
+			flags += string(flag)
+		}
+
+		if flags == current[_notifyKeyspaceEvents] {
+			continue
+		}
+		err = node.Do(ctx, builder.ConfigSet().ParameterValue().ParameterValue(_notifyKeyspaceEvents, flags).Build()).Error()
+		if err != nil {
+			return fmt.Errorf("Ошибка включения %s на %s: %w", _notifyKeyspaceEvents, addr, err)
+		}
+	}
+	return nil
+}
+
+// receive subscribes to the configured keyevent channels of node
+// and resubscribes after connection errors until ctx is done.
+func (b *EventBus) receive(ctx context.Context, node rueidis.Client) {
+	patterns := make([]string, 0, len(b.cfg.Events))
+	for _, eventType := range b.cfg.Events {
+		patterns = append(patterns, "__keyevent@*__:"+string(eventType))
+	}
+
+	for {
+		cmd := b.redis.conn.B().Psubscribe().Pattern(patterns...).Build()
+		err := node.Receive(ctx, cmd, func(msg rueidis.PubSubMessage) {
+			event, ok := parseEvent(msg)
+			if !ok {
+				return
+			}
+			select {
+			case b.events <- event:
+			default:
+				slog.Warn("Переполнен буфер событий редис", "type", event.Type, "key", event.Key)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error("Ошибка подписки на события редис", "error", err, "connection", b.redis.connectionName)
+		}
+		if !sleep(ctx, _defaultResubscribeWait) {
+			return
+		}
+	}
+}
+
+func (b *EventBus) dispatch(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.events:
+			b.mu.RLock()
+			routes := b.routes
+			b.mu.RUnlock()
+
+			for _, route := range routes {
+				if route.matches(event) {
+					route.handler(ctx, event)
+				}
+			}
+		}
+	}
+}
+
+func (r route) matches(event Event) bool {
+	if len(r.types) > 0 {
+		found := false
+		for _, eventType := range r.types {
+			if eventType == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchPattern(r.pattern, event.Key)
+}
+
+// parseEvent parses a message of a "__keyevent@<db>__:<event>"
+// channel.
+func parseEvent(msg rueidis.PubSubMessage) (Event, bool) {
+	channel, ok := strings.CutPrefix(msg.Channel, "__keyevent@")
+	if !ok {
+		return Event{}, false
+	}
+	db, eventType, ok := strings.Cut(channel, "__:")
+	if !ok {
+		return Event{}, false
+	}
+	dbNum, err := strconv.Atoi(db)
+	if err != nil {
+		return Event{}, false
+	}
+	return Event{
+		Type: EventType(eventType),
+		Key:  msg.Message,
+		DB:   dbNum,
+		Time: time.Now(),
+	}, true
+}
+
+// matchPattern reports whether key matches the redis-style glob
+// pattern with "*" and "?" wildcards. On a mismatch it backtracks
+// to the last "*" only, so it runs in O(len(pattern)*len(key)).
+func matchPattern(pattern, key string) bool {
+	p, k := 0, 0
+	star, starKey := -1, 0
+	for k < len(key) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, starKey = p, k
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == key[k]):
+			p++
+			k++
+		case star >= 0:
+			// The last "*" takes one more byte of the key.
+			starKey++
+			p, k = star+1, starKey
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
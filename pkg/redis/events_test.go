@@ -0,0 +1,38 @@
+package redis
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "user:1", true},
+		{"", "", true},
+		{"", "a", false},
+		{"user:*", "user:1", true},
+		{"user:*", "user:", true},
+		{"user:*", "users:1", false},
+		{"user:?", "user:1", true},
+		{"user:?", "user:12", false},
+		{"*:session", "app:user:session", true},
+		{"*:session", "app:user:sessions", false},
+		{"a*b*c", "a123b456c", true},
+		{"a*b*c", "a123c456b", false},
+		{"a*a*a*b", "aaaaaaaaaaaaaaaaaaaaaaaaaaaa", false},
+		{"**", "x", true},
+		{"?", "", false},
+		{"exact", "exact", true},
+		{"exact", "Exact", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.key, func(t *testing.T) {
+			if got := matchPattern(tt.pattern, tt.key); got != tt.want {
+				t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+type GeoUnit string
+
+const (
+	Meters     GeoUnit = "m"
+	Kilometers GeoUnit = "km"
+	Miles      GeoUnit = "mi"
+	Feet       GeoUnit = "ft"
+)
+
+// GeoSearchQuery is the area of GEOSEARCH. The center is
+// FromMember or, when it is empty, Longitude and Latitude. The
+// area is a circle of Radius or, when it is not positive, a box of
+// Width and Height. Count limits the results, with Any the first
+// Count matches are returned instead of the nearest ones.
+type GeoSearchQuery struct {
+	FromMember string
+	Longitude  float64
+	Latitude   float64
+	Radius     float64
+	Width      float64
+	Height     float64
+	Unit       GeoUnit
+	Count      int64
+	Any        bool
+	Desc       bool
+}
+
+func (q GeoSearchQuery) args() []string {
+	unit := q.Unit
+	if unit == "" {
+		unit = Meters
+	}
+
+	var args []string
+	if q.FromMember != "" {
+		args = append(args, "FROMMEMBER", q.FromMember)
+	} else {
+		args = append(args, "FROMLONLAT", formatFloat(q.Longitude), formatFloat(q.Latitude))
+	}
+	if q.Radius > 0 {
+		args = append(args, "BYRADIUS", formatFloat(q.Radius), string(unit))
+	} else {
+		args = append(args, "BYBOX", formatFloat(q.Width), formatFloat(q.Height), string(unit))
+	}
+	if q.Desc {
+		args = append(args, "DESC")
+	} else {
+		args = append(args, "ASC")
+	}
+	if q.Count > 0 {
+		args = append(args, "COUNT", strconv.FormatInt(q.Count, 10))
+		if q.Any {
+			args = append(args, "ANY")
+		}
+	}
+	return args
+}
+
+func (r *Redis) GeoAddCompleted(key string, locations ...rueidis.GeoLocation) rueidis.Completed {
+	cmd := r.conn.B().Geoadd().Key(key).LongitudeLatitudeMember()
+	for _, location := range locations {
+		cmd = cmd.LongitudeLatitudeMember(location.Longitude, location.Latitude, location.Name)
+	}
+	return cmd.Build()
+}
+
+// GeoAdd adds or updates the named locations and returns the
+// number of added members.
+func (r *Redis) GeoAdd(ctx context.Context, key string, locations ...rueidis.GeoLocation) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.GeoAddCompleted(key, locations...)).AsInt64()
+	r.writeTimingAndCounter(startTime, "redis_geoadd", err == nil)
+
+	return result, err
+}
+
+// GeoPos returns the coordinates of members, nil for members
+// that are not in key.
+func (r *Redis) GeoPos(ctx context.Context, key string, members ...string) ([]*rueidis.GeoLocation, error) {
+	startTime := time.Now()
+	values, err := r.do(ctx, r.conn.B().Geopos().Key(key).Member(members...).Build()).ToArray()
+	r.writeTimingAndCounter(startTime, "redis_geopos", err == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]*rueidis.GeoLocation, len(values))
+	for idx, value := range values {
+		coordinates, err := value.ToArray()
+		if err != nil || len(coordinates) < 2 {
+			continue
+		}
+		location := &rueidis.GeoLocation{Name: members[idx]}
+		location.Longitude, _ = coordinates[0].AsFloat64()
+		location.Latitude, _ = coordinates[1].AsFloat64()
+		locations[idx] = location
+	}
+	return locations, nil
+}
+
+// GeoDist returns the distance between two members. The error is
+// rueidis nil when one of them is not in key.
+func (r *Redis) GeoDist(ctx context.Context, key, member1, member2 string, unit GeoUnit) (float64, error) {
+	if unit == "" {
+		unit = Meters
+	}
+
+	startTime := time.Now()
+	cmd := r.conn.B().Arbitrary("GEODIST").Keys(key).Args(member1, member2, string(unit)).ReadOnly()
+	result, err := r.do(ctx, cmd).AsFloat64()
+	r.writeTimingAndCounter(startTime, "redis_geodist", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) GeoSearchCompleted(key string, query GeoSearchQuery) rueidis.Completed {
+	return r.conn.B().Arbitrary("GEOSEARCH").Keys(key).Args(query.args()...).
+		Args("WITHCOORD", "WITHDIST", "WITHHASH").ReadOnly()
+}
+
+// GeoSearch returns the members in the query area with their
+// coordinates, distance from the center in the query unit and
+// geohash.
+func (r *Redis) GeoSearch(ctx context.Context, key string, query GeoSearchQuery) ([]rueidis.GeoLocation, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.GeoSearchCompleted(key, query)).AsGeosearch()
+	r.writeTimingAndCounter(startTime, "redis_geosearch", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) GeoSearchStoreCompleted(destination, source string, query GeoSearchQuery, storeDist bool) rueidis.Completed {
+	cmd := r.conn.B().Arbitrary("GEOSEARCHSTORE").Keys(destination, source).Args(query.args()...)
+	if storeDist {
+		cmd = cmd.Args("STOREDIST")
+	}
+	return cmd.Build()
+}
+
+// GeoSearchStore stores the members in the query area in
+// destination and returns their number. With storeDist the scores
+// are distances instead of geohashes.
+func (r *Redis) GeoSearchStore(ctx context.Context, destination, source string, query GeoSearchQuery, storeDist bool) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.GeoSearchStoreCompleted(destination, source, query, storeDist)).AsInt64()
+	r.writeTimingAndCounter(startTime, "redis_geosearchstore", err == nil)
+
+	return result, err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (r *Multi) GeoAddCompleted(key string, locations ...rueidis.GeoLocation) rueidis.Completed {
+	return r.mainConn.GeoAddCompleted(key, locations...)
+}
+
+func (r *Multi) GeoAdd(ctx context.Context, key string, locations ...rueidis.GeoLocation) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.GeoAdd(ctx, key, locations...)
+	})
+}
+
+func (r *Multi) GeoPos(ctx context.Context, key string, members ...string) ([]*rueidis.GeoLocation, error) {
+	return readAll(ctx, r, "GeoPos", func(ctx context.Context, conn *Redis) ([]*rueidis.GeoLocation, error) {
+		return conn.GeoPos(ctx, key, members...)
+	})
+}
+
+func (r *Multi) GeoDist(ctx context.Context, key, member1, member2 string, unit GeoUnit) (float64, error) {
+	return readAll(ctx, r, "GeoDist", func(ctx context.Context, conn *Redis) (float64, error) {
+		return conn.GeoDist(ctx, key, member1, member2, unit)
+	})
+}
+
+func (r *Multi) GeoSearchCompleted(key string, query GeoSearchQuery) rueidis.Completed {
+	return r.mainConn.GeoSearchCompleted(key, query)
+}
+
+func (r *Multi) GeoSearch(ctx context.Context, key string, query GeoSearchQuery) ([]rueidis.GeoLocation, error) {
+	return readAll(ctx, r, "GeoSearch", func(ctx context.Context, conn *Redis) ([]rueidis.GeoLocation, error) {
+		return conn.GeoSearch(ctx, key, query)
+	})
+}
+
+func (r *Multi) GeoSearchStoreCompleted(destination, source string, query GeoSearchQuery, storeDist bool) rueidis.Completed {
+	return r.mainConn.GeoSearchStoreCompleted(destination, source, query, storeDist)
+}
+
+func (r *Multi) GeoSearchStore(ctx context.Context, destination, source string, query GeoSearchQuery, storeDist bool) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.GeoSearchStore(ctx, destination, source, query, storeDist)
+	})
+}
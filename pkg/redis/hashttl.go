@@ -0,0 +1,316 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+var ErrHashFieldTTLUnsupported = errors.New("hash field TTL requires redis 7.4 or newer")
+
+// _hGetExScript returns fields of a hash and sets their TTL to
+// ARGV[1] ms, or removes it when ARGV[1] is PERSIST.
+var _hGetExScript = NewScript("hgetex", `
+local values = redis.call('HMGET', KEYS[1], unpack(ARGV, 2))
+if ARGV[1] == 'PERSIST' then
+	redis.call('HPERSIST', KEYS[1], 'FIELDS', #ARGV - 1, unpack(ARGV, 2))
+else
+	redis.call('HPEXPIRE', KEYS[1], ARGV[1], 'FIELDS', #ARGV - 1, unpack(ARGV, 2))
+end
+return values
+`)
+
+// _hSetExScript sets fields of a hash with a TTL.
+var _hSetExScript = NewScript("hsetex", `
+local fields = {}
+for i = 2, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+	table.insert(fields, ARGV[i])
+end
+return redis.call('HPEXPIRE', KEYS[1], ARGV[1], 'FIELDS', #fields, unpack(fields))
+`)
+
+type ExpireCondition string
+
+// Conditions of HEXPIRE and the like, the empty condition always
+// sets the TTL.
+const (
+	ExpireAlways ExpireCondition = ""
+	ExpireNX     ExpireCondition = "NX"
+	ExpireXX     ExpireCondition = "XX"
+	ExpireGT     ExpireCondition = "GT"
+	ExpireLT     ExpireCondition = "LT"
+)
+
+// Replies of HEXPIRE and the like per field.
+const (
+	FieldMissing    = -2
+	FieldNoTTL      = -1
+	FieldNotChanged = 0
+	FieldTTLSet     = 1
+	FieldExpiredNow = 2
+)
+
+// capability caches the result of a server probe. A probe that
+// failed with a network error is repeated.
+type capability struct {
+	mu      sync.Mutex
+	checked bool
+	err     error
+}
+
+func (c *capability) check(probe func() (bool, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checked {
+		return c.err
+	}
+	final, err := probe()
+	if final {
+		c.checked, c.err = true, err
+	}
+	return err
+}
+
+// ProbeHashFieldTTL checks that every node supports hash field
+// TTL. It returns ErrHashFieldTTLUnsupported with the server
+// version for servers older than 7.4.
+func (r *Redis) ProbeHashFieldTTL(ctx context.Context) error {
+	return r.hashFieldTTL.check(func() (bool, error) {
+		for addr, node := range r.conn.Nodes() {
+			info, err := r.doOn(ctx, node, r.conn.B().Info().Section("server").Build()).ToString()
+			if err != nil {
+				return false, err
+			}
+			version := serverVersion(info)
+			if !versionAtLeast(version, 7, 4) {
+				return true, fmt.Errorf("%w: сервер %s версии %s", ErrHashFieldTTLUnsupported, addr, version)
+			}
+		}
+		return true, nil
+	})
+}
+
+// HExpireCompleted builds HEXPIRE, a ttl that isn't a whole number
+// of seconds is sent with HPEXPIRE instead, so it isn't truncated.
+func (r *Redis) HExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed {
+	if ttl%time.Second != 0 {
+		return r.HPExpireCompleted(key, ttl, cond, fields...)
+	}
+	return fieldsCompleted(r.conn.B(), "HEXPIRE", key, []string{strconv.FormatInt(int64(ttl/time.Second), 10)}, cond, fields)
+}
+
+// HExpire sets the TTL of fields and returns a reply per field:
+// FieldMissing, FieldNotChanged, FieldTTLSet or FieldExpiredNow.
+func (r *Redis) HExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return r.fieldsCommand(ctx, "redis_hexpire", r.HExpireCompleted(key, ttl, cond, fields...))
+}
+
+func (r *Redis) HPExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed {
+	return fieldsCompleted(r.conn.B(), "HPEXPIRE", key, []string{strconv.FormatInt(ttl.Milliseconds(), 10)}, cond, fields)
+}
+
+// HPExpire is HExpire with a TTL in milliseconds.
+func (r *Redis) HPExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return r.fieldsCommand(ctx, "redis_hpexpire", r.HPExpireCompleted(key, ttl, cond, fields...))
+}
+
+func (r *Redis) HExpireAtCompleted(key string, at time.Time, cond ExpireCondition, fields ...string) rueidis.Completed {
+	return fieldsCompleted(r.conn.B(), "HEXPIREAT", key, []string{strconv.FormatInt(at.Unix(), 10)}, cond, fields)
+}
+
+// HExpireAt sets the expiry time of fields.
+func (r *Redis) HExpireAt(ctx context.Context, key string, at time.Time, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return r.fieldsCommand(ctx, "redis_hexpireat", r.HExpireAtCompleted(key, at, cond, fields...))
+}
+
+func (r *Redis) HTTLCompleted(key string, fields ...string) rueidis.Completed {
+	return r.conn.B().Arbitrary("HTTL").Keys(key).Args(fieldsArgs(fields)...).ReadOnly()
+}
+
+// HTTL returns the TTL of fields in seconds, FieldNoTTL or
+// FieldMissing.
+func (r *Redis) HTTL(ctx context.Context, key string, fields ...string) ([]int64, error) {
+	return r.fieldsCommand(ctx, "redis_httl", r.HTTLCompleted(key, fields...))
+}
+
+func (r *Redis) HPersistCompleted(key string, fields ...string) rueidis.Completed {
+	return r.conn.B().Arbitrary("HPERSIST").Keys(key).Args(fieldsArgs(fields)...).Build()
+}
+
+// HPersist removes the TTL of fields and returns a reply per
+// field: FieldMissing, FieldNoTTL or 1 when it was removed.
+func (r *Redis) HPersist(ctx context.Context, key string, fields ...string) ([]int64, error) {
+	return r.fieldsCommand(ctx, "redis_hpersist", r.HPersistCompleted(key, fields...))
+}
+
+// HGetEx returns fields and refreshes their TTL. A ttl that is not
+// positive doesn't touch the TTL, use HGetExPersist to remove it.
+func (r *Redis) HGetEx(ctx context.Context, key string, ttl time.Duration, fields ...string) ([]rueidis.RedisMessage, error) {
+	if ttl <= 0 {
+		startTime := time.Now()
+		values, err := r.do(ctx, r.conn.B().Hmget().Key(key).Field(fields...).Build()).ToArray()
+		r.writeTimingAndCounter(startTime, "redis_hgetex", err == nil)
+
+		return values, err
+	}
+	return r.hGetEx(ctx, key, strconv.FormatInt(ttl.Milliseconds(), 10), fields)
+}
+
+// HGetExPersist returns fields and removes their TTL.
+func (r *Redis) HGetExPersist(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error) {
+	return r.hGetEx(ctx, key, "PERSIST", fields)
+}
+
+func (r *Redis) hGetEx(ctx context.Context, key, ttl string, fields []string) ([]rueidis.RedisMessage, error) {
+	if err := r.ProbeHashFieldTTL(ctx); err != nil {
+		return nil, err
+	}
+
+	args := append([]string{ttl}, fields...)
+	return r.Eval(ctx, _hGetExScript, []string{key}, args).ToArray()
+}
+
+// HSetEx sets fields with a TTL.
+func (r *Redis) HSetEx(ctx context.Context, key string, ttl time.Duration, kvs map[string]string) error {
+	if err := r.ProbeHashFieldTTL(ctx); err != nil {
+		return err
+	}
+
+	args := make([]string, 0, 1+2*len(kvs))
+	args = append(args, strconv.FormatInt(ttl.Milliseconds(), 10))
+	for k, v := range kvs {
+		args = append(args, k, v)
+	}
+	return r.Eval(ctx, _hSetExScript, []string{key}, args).Error()
+}
+
+func (r *Redis) fieldsCommand(ctx context.Context, query string, cmd rueidis.Completed) ([]int64, error) {
+	if err := r.ProbeHashFieldTTL(ctx); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result, err := r.do(ctx, cmd).AsIntSlice()
+	r.writeTimingAndCounter(startTime, query, err == nil)
+
+	return result, err
+}
+
+func fieldsCompleted(b rueidis.Builder, command, key string, args []string, cond ExpireCondition, fields []string) rueidis.Completed {
+	if cond != ExpireAlways {
+		args = append(args, string(cond))
+	}
+	return b.Arbitrary(command).Keys(key).Args(args...).Args(fieldsArgs(fields)...).Build()
+}
+
+func fieldsArgs(fields []string) []string {
+	return append([]string{"FIELDS", strconv.Itoa(len(fields))}, fields...)
+}
+
+// serverVersion returns redis_version of INFO server.
+func serverVersion(info string) string {
+	for _, line := range strings.Split(info, "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+			return version
+		}
+	}
+	return ""
+}
+
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err1 := strconv.Atoi(parts[0])
+	gotMinor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return gotMajor > major || gotMajor == major && gotMinor >= minor
+}
+
+func (r *Multi) ProbeHashFieldTTL(ctx context.Context) error {
+	for _, conn := range r.conn {
+		if err := conn.ProbeHashFieldTTL(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Multi) HExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed {
+	return r.mainConn.HExpireCompleted(key, ttl, cond, fields...)
+}
+
+func (r *Multi) HExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return writeAll(r, func(conn *Redis) ([]int64, error) {
+		return conn.HExpire(ctx, key, ttl, cond, fields...)
+	})
+}
+
+func (r *Multi) HPExpireCompleted(key string, ttl time.Duration, cond ExpireCondition, fields ...string) rueidis.Completed {
+	return r.mainConn.HPExpireCompleted(key, ttl, cond, fields...)
+}
+
+func (r *Multi) HPExpire(ctx context.Context, key string, ttl time.Duration, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return writeAll(r, func(conn *Redis) ([]int64, error) {
+		return conn.HPExpire(ctx, key, ttl, cond, fields...)
+	})
+}
+
+func (r *Multi) HExpireAtCompleted(key string, at time.Time, cond ExpireCondition, fields ...string) rueidis.Completed {
+	return r.mainConn.HExpireAtCompleted(key, at, cond, fields...)
+}
+
+func (r *Multi) HExpireAt(ctx context.Context, key string, at time.Time, cond ExpireCondition, fields ...string) ([]int64, error) {
+	return writeAll(r, func(conn *Redis) ([]int64, error) {
+		return conn.HExpireAt(ctx, key, at, cond, fields...)
+	})
+}
+
+func (r *Multi) HTTLCompleted(key string, fields ...string) rueidis.Completed {
+	return r.mainConn.HTTLCompleted(key, fields...)
+}
+
+func (r *Multi) HTTL(ctx context.Context, key string, fields ...string) ([]int64, error) {
+	return readAll(ctx, r, "HTTL", func(ctx context.Context, conn *Redis) ([]int64, error) {
+		return conn.HTTL(ctx, key, fields...)
+	})
+}
+
+func (r *Multi) HPersistCompleted(key string, fields ...string) rueidis.Completed {
+	return r.mainConn.HPersistCompleted(key, fields...)
+}
+
+func (r *Multi) HPersist(ctx context.Context, key string, fields ...string) ([]int64, error) {
+	return writeAll(r, func(conn *Redis) ([]int64, error) {
+		return conn.HPersist(ctx, key, fields...)
+	})
+}
+
+func (r *Multi) HGetEx(ctx context.Context, key string, ttl time.Duration, fields ...string) ([]rueidis.RedisMessage, error) {
+	return writeAll(r, func(conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.HGetEx(ctx, key, ttl, fields...)
+	})
+}
+
+func (r *Multi) HGetExPersist(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error) {
+	return writeAll(r, func(conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.HGetExPersist(ctx, key, fields...)
+	})
+}
+
+func (r *Multi) HSetEx(ctx context.Context, key string, ttl time.Duration, kvs map[string]string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.HSetEx(ctx, key, ttl, kvs)
+	})
+}
@@ -0,0 +1,32 @@
+package redis
+
+import "testing"
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		major   int
+		minor   int
+		want    bool
+	}{
+		{"7.4.0", 7, 4, true},
+		{"7.4", 7, 4, true},
+		{"7.2.5", 7, 4, false},
+		{"8.0.1", 7, 4, true},
+		{"6.9.9", 7, 4, false},
+		{"7.10.0", 7, 4, true},
+		{"255.255.255", 7, 4, true},
+		{"7", 7, 4, false},
+		{"", 7, 4, false},
+		{"v7.4.0", 7, 4, false},
+		{"7.x.0", 7, 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := versionAtLeast(tt.version, tt.major, tt.minor); got != tt.want {
+				t.Errorf("versionAtLeast(%q, %d, %d) = %v, want %v", tt.version, tt.major, tt.minor, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+)
+
+const (
+	_defaultHotKeysTopN  = 20
+	_defaultHotKeysWidth = 2048
+	_defaultHotKeysDepth = 4
+	_hotKeysShards       = 16
+)
+
+type HotKeysConfig struct {
+	Enabled bool `env:"REDIS_HOT_KEYS" yaml:"enabled"`
+	TopN    int  `yaml:"top_n"`
+	Width   int  `yaml:"width"`
+	Depth   int  `yaml:"depth"`
+}
+
+// KeyCount is an estimated number of commands issued for a key.
+type KeyCount struct {
+	Key   string
+	Count uint64
+}
+
+// HotKeys estimates per-key command frequency with a count-min
+// sketch and keeps the TopN most frequent keys seen since the
+// last Reset. Keys are spread over shards with their own lock,
+// sketch and top list, so observing doesn't contend on one mutex.
+type HotKeys struct {
+	seed   maphash.Seed
+	shards []hotKeysShard
+	topN   int
+}
+
+type hotKeysShard struct {
+	mu     sync.Mutex
+	seeds  []maphash.Seed
+	counts [][]uint64
+	top    map[string]uint64
+	// coldest is a lower bound of the counts in top, counts only
+	// grow, so it is refreshed only when a key may displace one.
+	coldest uint64
+}
+
+func NewHotKeys(cfg HotKeysConfig) *HotKeys {
+	if cfg.TopN <= 0 {
+		cfg.TopN = _defaultHotKeysTopN
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = _defaultHotKeysWidth
+	}
+	if cfg.Depth <= 0 {
+		cfg.Depth = _defaultHotKeysDepth
+	}
+
+	h := &HotKeys{
+		seed:   maphash.MakeSeed(),
+		shards: make([]hotKeysShard, _hotKeysShards),
+		topN:   cfg.TopN,
+	}
+	width := max(cfg.Width/_hotKeysShards, 1)
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.seeds = make([]maphash.Seed, cfg.Depth)
+		shard.counts = make([][]uint64, cfg.Depth)
+		shard.top = make(map[string]uint64, cfg.TopN+1)
+		for j := range shard.counts {
+			shard.seeds[j] = maphash.MakeSeed()
+			shard.counts[j] = make([]uint64, width)
+		}
+	}
+	return h
+}
+
+// Observe counts one command issued for key.
+func (h *HotKeys) Observe(key string) {
+	shard := &h.shards[maphash.String(h.seed, key)%_hotKeysShards]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	estimate := ^uint64(0)
+	for i, row := range shard.counts {
+		idx := maphash.String(shard.seeds[i], key) % uint64(len(row))
+		row[idx]++
+		estimate = min(estimate, row[idx])
+	}
+
+	if _, ok := shard.top[key]; ok || len(shard.top) < h.topN {
+		shard.top[key] = estimate
+		return
+	}
+	if estimate <= shard.coldest {
+		return
+	}
+
+	coldest, coldestCount := shard.coldestKey()
+	if estimate > coldestCount {
+		delete(shard.top, coldest)
+		shard.top[key] = estimate
+		_, coldestCount = shard.coldestKey()
+	}
+	shard.coldest = coldestCount
+}
+
+func (s *hotKeysShard) coldestKey() (string, uint64) {
+	coldest, coldestCount := "", ^uint64(0)
+	for k, count := range s.top {
+		if count < coldestCount {
+			coldest, coldestCount = k, count
+		}
+	}
+	return coldest, coldestCount
+}
+
+// Top returns the hottest keys ordered by estimated count.
+func (h *HotKeys) Top() []KeyCount {
+	var result []KeyCount
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.mu.Lock()
+		for key, count := range shard.top {
+			result = append(result, KeyCount{Key: key, Count: count})
+		}
+		shard.mu.Unlock()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	if len(result) > h.topN {
+		result = result[:h.topN]
+	}
+	return result
+}
+
+// Reset starts a new observation window.
+func (h *HotKeys) Reset() {
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.mu.Lock()
+		for _, row := range shard.counts {
+			clear(row)
+		}
+		clear(shard.top)
+		shard.coldest = 0
+		shard.mu.Unlock()
+	}
+}
+
+// observeKey records the key of a command when hot key tracking
+// is enabled. Commands without a key, such as SCAN or KEYS, are
+// skipped.
+func (r *Redis) observeKey(commands []string) {
+	if r.hotKeys == nil {
+		return
+	}
+	if len(commands) < 2 {
+		return
+	}
+	switch CommandFamily(commands[0]) {
+	case FamilyDefault, FamilyScan:
+		return
+	}
+	if commands[0] == "KEYS" {
+		return
+	}
+	r.hotKeys.Observe(commands[1])
+}
+
+// HotKeys returns the hot key tracker of the connection or nil
+// when tracking is disabled.
+func (r *Redis) HotKeys() *HotKeys {
+	return r.hotKeys
+}
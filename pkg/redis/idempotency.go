@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	_defaultProgressTTL = time.Minute
+	_defaultResultTTL   = 24 * time.Hour
+	_stateStarted       = "started"
+	_stateDone          = "done"
+)
+
+// _beginScript marks the key in progress by the owner unless it
+// is already marked. It returns the state with the result or the
+// start time and owner of the existing entry, and the time of the
+// redis clock, so the clocks of instances don't matter.
+var _beginScript = NewScript("idempotency_begin", `
+local time = redis.call('TIME')
+local now = tostring(time[1] * 1000 + math.floor(time[2] / 1000))
+local entry = redis.call('HMGET', KEYS[1], 'state', 'result', 'started', 'owner')
+if entry[1] then
+	return {entry[1], entry[2] or '', entry[3] or '0', entry[4] or '', now}
+end
+redis.call('HSET', KEYS[1], 'state', 'progress', 'started', now, 'owner', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return {'started', '', now, ARGV[1], now}
+`)
+
+// _completeIdempotencyScript stores the result when the key is in
+// progress by the owner.
+var _completeIdempotencyScript = NewScript("idempotency_complete", `
+local entry = redis.call('HMGET', KEYS[1], 'state', 'owner')
+if entry[1] ~= 'progress' or entry[2] ~= ARGV[3] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'state', 'done', 'result', ARGV[1])
+redis.call('HDEL', KEYS[1], 'owner')
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// _abortScript removes an in-progress mark of the owner.
+var _abortScript = NewScript("idempotency_abort", `
+local entry = redis.call('HMGET', KEYS[1], 'state', 'owner')
+if entry[1] ~= 'progress' or entry[2] ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+var (
+	ErrNoQuorum = errors.New("idempotency quorum is not reached")
+	ErrNotOwner = errors.New("idempotency key is not in progress by the owner")
+)
+
+type IdempotencyState int
+
+const (
+	// IdempotencyStarted means the caller owns the request and
+	// must Complete or Abort it with the entry Owner.
+	IdempotencyStarted IdempotencyState = iota
+	// IdempotencyInProgress means the request is being processed
+	// by another caller.
+	IdempotencyInProgress
+	// IdempotencyCompleted means the request was processed, the
+	// stored result is returned.
+	IdempotencyCompleted
+)
+
+// IdempotencyConfig configures an IdempotencyStore. An in-progress
+// mark expires after ProgressTTL, so a crashed caller doesn't block
+// retries forever, and is reported as stuck after StuckAfter.
+type IdempotencyConfig struct {
+	Name        string        `yaml:"name"`
+	ProgressTTL time.Duration `yaml:"progress_ttl"`
+	ResultTTL   time.Duration `yaml:"result_ttl"`
+	StuckAfter  time.Duration `yaml:"stuck_after"`
+}
+
+// IdempotencyEntry is the state of a request key.
+type IdempotencyEntry[V any] struct {
+	State     IdempotencyState
+	Result    V
+	StartedAt time.Time
+	// Owner is the token of the caller that started the request,
+	// it is set only for IdempotencyStarted.
+	Owner string
+	// Stuck is set for requests in progress for longer than
+	// StuckAfter.
+	Stuck bool
+}
+
+// IdempotencyStore makes request processing idempotent: Begin marks
+// a request in progress, Complete stores its result and later
+// Begin calls return it.
+//
+// A store created from Multi writes to every connection and needs
+// a majority of them to agree, so a single failed redis doesn't
+// break the guarantee. The number of connections has to be odd:
+// with two connections the majority is both of them.
+type IdempotencyStore[V any] struct {
+	conns  []*Redis
+	cfg    IdempotencyConfig
+	codec  Codec[V]
+	prefix string
+}
+
+func NewIdempotencyStore[V any](r *Redis, cfg IdempotencyConfig, codec Codec[V]) *IdempotencyStore[V] {
+	return newIdempotencyStore([]*Redis{r}, cfg, codec)
+}
+
+func NewMultiIdempotencyStore[V any](r *Multi, cfg IdempotencyConfig, codec Codec[V]) (*IdempotencyStore[V], error) {
+	if len(r.conn)%2 == 0 {
+		return nil, fmt.Errorf("Для кворума идемпотентности нужно нечетное число подключений, передано %d", len(r.conn))
+	}
+	return newIdempotencyStore(r.conn, cfg, codec), nil
+}
+
+func newIdempotencyStore[V any](conns []*Redis, cfg IdempotencyConfig, codec Codec[V]) *IdempotencyStore[V] {
+	if cfg.ProgressTTL <= 0 {
+		cfg.ProgressTTL = _defaultProgressTTL
+	}
+	if cfg.ResultTTL <= 0 {
+		cfg.ResultTTL = _defaultResultTTL
+	}
+	if cfg.StuckAfter <= 0 || cfg.StuckAfter > cfg.ProgressTTL {
+		cfg.StuckAfter = cfg.ProgressTTL / 2
+	}
+
+	return &IdempotencyStore[V]{
+		conns:  conns,
+		cfg:    cfg,
+		codec:  codec,
+		prefix: "idempotency:" + cfg.Name + ":",
+	}
+}
+
+// Begin marks key in progress. When the request is already in
+// progress or completed, the existing entry is returned instead.
+func (s *IdempotencyStore[V]) Begin(ctx context.Context, key string) (IdempotencyEntry[V], error) {
+	owner := instanceID()
+	args := []string{owner, strconv.FormatInt(s.cfg.ProgressTTL.Milliseconds(), 10)}
+
+	var (
+		entry     IdempotencyEntry[V]
+		startedAt time.Time
+		started   int
+		done      bool
+		errAll    error
+	)
+	for _, conn := range s.conns {
+		values, err := conn.Eval(ctx, _beginScript, []string{s.prefix + key}, args).AsStrSlice()
+		if err == nil && len(values) != 5 {
+			err = fmt.Errorf("Ошибка разбора ответа идемпотентности: %v", values)
+		}
+		if err != nil {
+			errAll = errors.Join(errAll, err)
+			continue
+		}
+
+		state, result := values[0], values[1]
+		ms, _ := strconv.ParseInt(values[2], 10, 64)
+		now, _ := strconv.ParseInt(values[4], 10, 64)
+		switch {
+		case state == _stateDone && !done:
+			if entry.Result, err = s.codec.Decode(result); err != nil {
+				s.abort(ctx, key, owner)
+				return entry, err
+			}
+			entry.State, done = IdempotencyCompleted, true
+		case state == _stateStarted:
+			started++
+			if startedAt.IsZero() {
+				startedAt = time.UnixMilli(ms)
+			}
+		case !done:
+			entry.State, entry.StartedAt = IdempotencyInProgress, time.UnixMilli(ms)
+			entry.Stuck = time.Duration(now-ms)*time.Millisecond > s.cfg.StuckAfter
+		}
+	}
+
+	if done {
+		s.abort(ctx, key, owner)
+		return entry, nil
+	}
+	if started >= s.quorum() {
+		return IdempotencyEntry[V]{State: IdempotencyStarted, StartedAt: startedAt, Owner: owner}, nil
+	}
+
+	// Another caller holds the majority or redis is unavailable.
+	// The marks of this caller are rolled back.
+	s.abort(ctx, key, owner)
+	if entry.State == IdempotencyInProgress {
+		return entry, nil
+	}
+	return entry, errors.Join(ErrNoQuorum, errAll)
+}
+
+// Complete stores the result of key started by owner. Later Begin
+// calls return it until ResultTTL passes. ErrNotOwner is returned
+// when the mark of owner expired or was taken by another caller.
+func (s *IdempotencyStore[V]) Complete(ctx context.Context, key, owner string, result V) error {
+	raw, err := s.codec.Encode(result)
+	if err != nil {
+		return err
+	}
+
+	args := []string{raw, strconv.FormatInt(s.cfg.ResultTTL.Milliseconds(), 10), owner}
+	return s.quorumDo(func(conn *Redis) error {
+		return owned(conn.Eval(ctx, _completeIdempotencyScript, []string{s.prefix + key}, args).AsBool())
+	})
+}
+
+// Abort removes the in-progress mark of key started by owner, e.g.
+// after a failure that should be retried.
+func (s *IdempotencyStore[V]) Abort(ctx context.Context, key, owner string) error {
+	return s.quorumDo(func(conn *Redis) error {
+		return owned(conn.Eval(ctx, _abortScript, []string{s.prefix + key}, []string{owner}).AsBool())
+	})
+}
+
+func owned(ok bool, err error) error {
+	if err == nil && !ok {
+		return ErrNotOwner
+	}
+	return err
+}
+
+func (s *IdempotencyStore[V]) abort(ctx context.Context, key, owner string) {
+	for _, conn := range s.conns {
+		conn.Eval(ctx, _abortScript, []string{s.prefix + key}, []string{owner})
+	}
+}
+
+func (s *IdempotencyStore[V]) quorumDo(call func(conn *Redis) error) error {
+	var (
+		succeeded int
+		errAll    error
+	)
+	for _, conn := range s.conns {
+		if err := call(conn); err != nil {
+			errAll = errors.Join(errAll, err)
+			continue
+		}
+		succeeded++
+	}
+	if succeeded < s.quorum() {
+		return errors.Join(ErrNoQuorum, errAll)
+	}
+	return nil
+}
+
+func (s *IdempotencyStore[V]) quorum() int {
+	return len(s.conns)/2 + 1
+}
@@ -0,0 +1,425 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"skeleton/pkg/hostname"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultLeaseTTL   = 15 * time.Second
+	_defaultLeaderWait = 10 * time.Second
+	_leaderNotAcquired = 0
+)
+
+// _acquireScript takes the lease when it is free and renews it
+// when it is held by the instance. It returns the fencing token of
+// the held lease or 0. Every new lease gets a greater token.
+var _acquireScript = NewScript("leader_acquire", `
+local current = redis.call('GET', KEYS[1])
+if current then
+	local id, token = string.match(current, '^(.*)|(%d+)$')
+	if id ~= ARGV[1] then
+		return 0
+	end
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(token)
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. '|' .. token, 'PX', ARGV[2])
+return token
+`)
+
+// _releaseScript deletes the lease when it is still held with the
+// given token.
+var _releaseScript = NewScript("leader_release", `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// leaderMetrics is implemented by metrics that export whether the
+// instance is the leader of an election.
+type leaderMetrics interface {
+	SetLeader(election string, leader bool)
+}
+
+// ElectorConfig configures an election. The lease is renewed
+// every RenewInterval, which defaults to a third of LeaseTTL.
+type ElectorConfig struct {
+	Name          string        `yaml:"name"`
+	LeaseTTL      time.Duration `yaml:"lease_ttl"`
+	RenewInterval time.Duration `yaml:"renew_interval"`
+}
+
+type term struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	token  int64
+}
+
+// Elector elects one leader among the instances with the same
+// election name. The leader holds a lease in redis and renews it,
+// another instance takes the lease after it expires. Every lease
+// has a fencing token greater than the previous one, pass it to
+// the resources guarded by the election to reject writes of a
+// stale leader.
+//
+// Elector implements service.ServiceWithDown, the instance takes
+// part in the election between Up and Down, Down releases the
+// lease.
+type Elector struct {
+	redis   *Redis
+	cfg     ElectorConfig
+	metrics leaderMetrics
+	id      string
+	lease   string
+	counter string
+
+	mu        sync.Mutex
+	term      *term
+	renewedAt time.Time
+	changed   chan struct{}
+	elected   []func(ctx context.Context, token int64)
+	revoked   []func()
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func NewElector(r *Redis, cfg ElectorConfig, metrics leaderMetrics) *Elector {
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = _defaultLeaseTTL
+	}
+	if cfg.RenewInterval <= 0 || cfg.RenewInterval >= cfg.LeaseTTL {
+		cfg.RenewInterval = cfg.LeaseTTL / 3
+	}
+
+	prefix := "leader:{" + cfg.Name + "}"
+	return &Elector{
+		redis:   r,
+		cfg:     cfg,
+		metrics: metrics,
+		id:      instanceID(),
+		lease:   prefix,
+		counter: prefix + ":token",
+		changed: make(chan struct{}),
+	}
+}
+
+// OnElected adds a callback that is called when the instance
+// becomes the leader. ctx is done when the leadership is lost.
+// Callbacks run in the elector goroutine and must not block.
+func (e *Elector) OnElected(fn func(ctx context.Context, token int64)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.elected = append(e.elected, fn)
+}
+
+// OnRevoked adds a callback that is called when the instance
+// loses the leadership.
+func (e *Elector) OnRevoked(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.revoked = append(e.revoked, fn)
+}
+
+// IsLeader reports whether the instance is the leader.
+func (e *Elector) IsLeader() bool {
+	_, ok := e.Token()
+	return ok
+}
+
+// Token returns the fencing token of the current term.
+func (e *Elector) Token() (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.term == nil {
+		return 0, false
+	}
+	return e.term.token, true
+}
+
+// Leading waits until the instance is the leader and returns a
+// context that is done when the leadership is lost.
+func (e *Elector) Leading(ctx context.Context) (context.Context, error) {
+	for {
+		e.mu.Lock()
+		current, changed := e.term, e.changed
+		e.mu.Unlock()
+
+		if current != nil {
+			return current.ctx, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (e *Elector) Up(ctx context.Context) error {
+	e.mu.Lock()
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	e.mu.Unlock()
+	defer close(e.done)
+	e.setMetric(false)
+
+	ticker := time.NewTicker(e.cfg.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		e.renew(ctx)
+
+		select {
+		case <-ctx.Done():
+			e.resign(context.WithoutCancel(ctx))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) Down(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Elector) renew(ctx context.Context) {
+	startTime := time.Now()
+	token, err := e.redis.Eval(ctx, _acquireScript,
+		[]string{e.lease, e.counter},
+		[]string{e.id, strconv.FormatInt(e.cfg.LeaseTTL.Milliseconds(), 10)},
+	).AsInt64()
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("Ошибка продления лидерства", "error", err, "election", e.cfg.Name)
+
+		// The lease may expire before the next attempt.
+		e.mu.Lock()
+		expiring := e.term != nil && time.Since(e.renewedAt)+e.cfg.RenewInterval >= e.cfg.LeaseTTL
+		e.mu.Unlock()
+		if expiring {
+			e.revoke()
+		}
+		return
+	}
+
+	current, ok := e.Token()
+	switch {
+	case token == _leaderNotAcquired:
+		if ok {
+			e.revoke()
+		}
+	case ok && current == token:
+		e.mu.Lock()
+		e.renewedAt = startTime
+		e.mu.Unlock()
+	default:
+		// The lease expired between renewals and was taken again.
+		if ok {
+			e.revoke()
+		}
+		e.elect(ctx, token, startTime)
+	}
+}
+
+func (e *Elector) elect(ctx context.Context, token int64, at time.Time) {
+	termCtx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	e.term = &term{ctx: termCtx, cancel: cancel, token: token}
+	e.renewedAt = at
+	close(e.changed)
+	e.changed = make(chan struct{})
+	callbacks := e.elected
+	e.mu.Unlock()
+
+	slog.Info("Экземпляр стал лидером", "election", e.cfg.Name, "token", token)
+	e.setMetric(true)
+	for _, fn := range callbacks {
+		fn(termCtx, token)
+	}
+}
+
+func (e *Elector) revoke() {
+	e.mu.Lock()
+	current := e.term
+	e.term = nil
+	close(e.changed)
+	e.changed = make(chan struct{})
+	callbacks := e.revoked
+	e.mu.Unlock()
+
+	if current == nil {
+		return
+	}
+	current.cancel()
+
+	slog.Warn("Экземпляр потерял лидерство", "election", e.cfg.Name, "token", current.token)
+	e.setMetric(false)
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// resign releases the lease so another instance doesn't wait for
+// it to expire.
+func (e *Elector) resign(ctx context.Context) {
+	token, ok := e.Token()
+	e.revoke()
+	if !ok {
+		return
+	}
+
+	err := e.redis.Eval(ctx, _releaseScript,
+		[]string{e.lease},
+		[]string{e.id + "|" + strconv.FormatInt(token, 10)},
+	).Error()
+	if err != nil {
+		slog.Error("Ошибка освобождения лидерства", "error", err, "election", e.cfg.Name)
+	}
+}
+
+func (e *Elector) setMetric(leader bool) {
+	if e.metrics != nil {
+		e.metrics.SetLeader(e.cfg.Name, leader)
+	}
+}
+
+// instanceID returns an ID of the process that is unique among
+// instances.
+func instanceID() string {
+	return fmt.Sprintf("%s-%d-%x", hostname.GetHostName(), os.Getpid(), rand.Uint32())
+}
+
+// upper is a service.Service.
+type upper interface {
+	Up(context.Context) error
+}
+
+// downer is a service.ServiceWithDown.
+type downer interface {
+	Down(context.Context) error
+}
+
+// LeaderService runs a service only while the instance is the
+// leader of an election. The service is started on every election
+// with a context that is done when the leadership is lost, Down of
+// the service is also called then when it has one. The service
+// must return from Up when its context is done.
+//
+// LeaderService implements service.ServiceWithDown.
+type LeaderService struct {
+	elector *Elector
+	service upper
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewLeaderService(elector *Elector, service upper) *LeaderService {
+	return &LeaderService{
+		elector: elector,
+		service: service,
+	}
+}
+
+func (s *LeaderService) Up(ctx context.Context) error {
+	s.mu.Lock()
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+	defer close(s.done)
+
+	for {
+		leaderCtx, err := s.elector.Leading(ctx)
+		if err != nil {
+			return nil
+		}
+		s.run(ctx, leaderCtx)
+
+		// A service that returned early isn't restarted in the same
+		// term.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-leaderCtx.Done():
+		}
+	}
+}
+
+func (s *LeaderService) Down(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *LeaderService) run(ctx, leaderCtx context.Context) {
+	runCtx, cancel := context.WithCancel(leaderCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- s.service.Up(runCtx)
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			slog.Error("Ошибка сервиса лидера", "error", err, "election", s.elector.cfg.Name)
+		}
+		return
+	case <-runCtx.Done():
+	}
+
+	if service, ok := s.service.(downer); ok {
+		downCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), _defaultLeaderWait)
+		defer cancel()
+		if err := service.Down(downCtx); err != nil {
+			slog.Error("Ошибка остановки сервиса лидера", "error", err, "election", s.elector.cfg.Name)
+		}
+	}
+	<-result
+}
@@ -0,0 +1,243 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultDailyRetention  = 8 * 24 * time.Hour
+	_defaultWeeklyRetention = 5 * 7 * 24 * time.Hour
+)
+
+type Window string
+
+// Windows of a leaderboard. Daily and weekly windows start at
+// midnight UTC, weeks start on Monday.
+const (
+	WindowAll    Window = "all"
+	WindowDaily  Window = "daily"
+	WindowWeekly Window = "weekly"
+)
+
+// LeaderboardConfig enables windowed boards. A windowed board
+// expires Retention after the end of its window.
+type LeaderboardConfig struct {
+	Name            string        `yaml:"name"`
+	Daily           bool          `yaml:"daily"`
+	Weekly          bool          `yaml:"weekly"`
+	DailyRetention  time.Duration `yaml:"daily_retention"`
+	WeeklyRetention time.Duration `yaml:"weekly_retention"`
+}
+
+// Entry is a member of a board. Rank starts at 1, members with
+// equal scores share the rank and the next rank is skipped
+// (1, 2, 2, 4).
+type Entry struct {
+	Member string
+	Score  float64
+	Rank   int64
+}
+
+// Leaderboard ranks members by score in an all-time board and in
+// daily and weekly boards. Score updates go to every enabled board
+// of the current window in one pipeline. Boards are addressed by
+// keys returned by Board, Days and Weeks, all keys of a
+// leaderboard share a cluster slot.
+type Leaderboard struct {
+	redis  *Redis
+	cfg    LeaderboardConfig
+	prefix string
+}
+
+func NewLeaderboard(r *Redis, cfg LeaderboardConfig) *Leaderboard {
+	if cfg.DailyRetention <= 0 {
+		cfg.DailyRetention = _defaultDailyRetention
+	}
+	if cfg.WeeklyRetention <= 0 {
+		cfg.WeeklyRetention = _defaultWeeklyRetention
+	}
+
+	return &Leaderboard{
+		redis:  r,
+		cfg:    cfg,
+		prefix: "leaderboard:{" + cfg.Name + "}",
+	}
+}
+
+// Board returns the key of the board of window that contains at.
+func (l *Leaderboard) Board(window Window, at time.Time) string {
+	at = at.UTC()
+	switch window {
+	case WindowDaily:
+		return l.prefix + ":daily:" + at.Format(time.DateOnly)
+	case WindowWeekly:
+		year, week := at.ISOWeek()
+		return fmt.Sprintf("%s:weekly:%d-W%02d", l.prefix, year, week)
+	default:
+		return l.prefix
+	}
+}
+
+// Days returns the keys of n daily boards ending with the day of
+// at.
+func (l *Leaderboard) Days(at time.Time, n int) []string {
+	boards := make([]string, n)
+	for idx := range boards {
+		boards[idx] = l.Board(WindowDaily, at.AddDate(0, 0, -idx))
+	}
+	return boards
+}
+
+// Weeks returns the keys of n weekly boards ending with the week
+// of at.
+func (l *Leaderboard) Weeks(at time.Time, n int) []string {
+	boards := make([]string, n)
+	for idx := range boards {
+		boards[idx] = l.Board(WindowWeekly, at.AddDate(0, 0, -7*idx))
+	}
+	return boards
+}
+
+// Incr adds delta to the score of member in every board of the
+// current window and returns the all-time score.
+func (l *Leaderboard) Incr(ctx context.Context, member string, delta float64) (float64, error) {
+	results, err := l.update(ctx, func(board string) rueidis.Completed {
+		return l.redis.conn.B().Zincrby().Key(board).Increment(delta).Member(member).Build()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return results[0].AsFloat64()
+}
+
+// Set sets the score of member in every board of the current
+// window.
+func (l *Leaderboard) Set(ctx context.Context, member string, score float64) error {
+	_, err := l.update(ctx, func(board string) rueidis.Completed {
+		return l.redis.conn.B().Zadd().Key(board).ScoreMember().ScoreMember(score, member).Build()
+	})
+	return err
+}
+
+// Remove removes member from every board of the current window.
+func (l *Leaderboard) Remove(ctx context.Context, member string) error {
+	_, err := l.update(ctx, func(board string) rueidis.Completed {
+		return l.redis.conn.B().Zrem().Key(board).Member(member).Build()
+	})
+	return err
+}
+
+// Rank returns the entry of member in board. The error is rueidis
+// nil when the member is not in the board.
+func (l *Leaderboard) Rank(ctx context.Context, board, member string) (Entry, error) {
+	score, err := l.redis.ZScore(ctx, board, member)
+	if err != nil {
+		return Entry{}, err
+	}
+	rank, err := l.rank(ctx, board, score)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Member: member, Score: score, Rank: rank}, nil
+}
+
+// Top returns count entries of board starting at offset.
+func (l *Leaderboard) Top(ctx context.Context, board string, offset, count int64) ([]Entry, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	return l.page(ctx, board, offset, offset+count-1)
+}
+
+// Around returns the entries of board within radius positions
+// around member, the member included.
+func (l *Leaderboard) Around(ctx context.Context, board, member string, radius int64) ([]Entry, error) {
+	position, err := l.redis.ZRevRank(ctx, board, member)
+	if err != nil {
+		return nil, err
+	}
+	return l.page(ctx, board, max(position-radius, 0), position+radius)
+}
+
+// Count returns the number of members in board.
+func (l *Leaderboard) Count(ctx context.Context, board string) (int64, error) {
+	return l.redis.ZCard(ctx, board)
+}
+
+// Aggregate stores the weighted union of boards in destination,
+// e.g. the last seven daily boards, and returns the number of
+// members. The destination expires after ttl when it is positive.
+func (l *Leaderboard) Aggregate(ctx context.Context, destination string, boards []string, opts ZStoreOptions, ttl time.Duration) (int64, error) {
+	count, err := l.redis.ZUnionStoreWithOptions(ctx, destination, boards, opts)
+	if err != nil || ttl <= 0 {
+		return count, err
+	}
+	return count, l.redis.Expire(ctx, destination, ttl)
+}
+
+// AggregateKey returns a key for Aggregate in the slot of the
+// leaderboard.
+func (l *Leaderboard) AggregateKey(name string) string {
+	return l.prefix + ":aggregate:" + name
+}
+
+func (l *Leaderboard) page(ctx context.Context, board string, start, stop int64) ([]Entry, error) {
+	scores, err := l.redis.ZRevRangeWithScores(ctx, board, start, stop)
+	if err != nil || len(scores) == 0 {
+		return nil, err
+	}
+
+	rank, err := l.rank(ctx, board, scores[0].Score)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(scores))
+	for idx, score := range scores {
+		if idx > 0 && score.Score != scores[idx-1].Score {
+			rank = start + int64(idx) + 1
+		}
+		entries[idx] = Entry{Member: score.Member, Score: score.Score, Rank: rank}
+	}
+	return entries, nil
+}
+
+// rank returns the rank of score: one more than the number of
+// higher scores.
+func (l *Leaderboard) rank(ctx context.Context, board string, score float64) (int64, error) {
+	higher, err := l.redis.ZCount(ctx, board, "("+formatFloat(score), "+inf")
+	return higher + 1, err
+}
+
+// update runs the command built by cmd on every board of the
+// current window and refreshes the expiry of windowed boards. The
+// all-time result is first.
+func (l *Leaderboard) update(ctx context.Context, cmd func(board string) rueidis.Completed) ([]rueidis.RedisResult, error) {
+	now := time.Now().UTC()
+	cmds := rueidis.Commands{cmd(l.Board(WindowAll, now))}
+	if l.cfg.Daily {
+		board := l.Board(WindowDaily, now)
+		end := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		cmds = append(cmds, cmd(board), l.redis.ExpireAtCompleted(board, end.Add(l.cfg.DailyRetention)))
+	}
+	if l.cfg.Weekly {
+		board := l.Board(WindowWeekly, now)
+		days := (8 - int(now.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		end := time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, time.UTC)
+		cmds = append(cmds, cmd(board), l.redis.ExpireAtCompleted(board, end.Add(l.cfg.WeeklyRetention)))
+	}
+
+	startTime := time.Now()
+	results := l.redis.DoMulti(ctx, cmds...)
+	err := HasError(results)
+	l.redis.writeTimingAndCounter(startTime, "redis_leaderboard_update", err == nil)
+
+	return results, err
+}
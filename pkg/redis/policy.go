@@ -0,0 +1,333 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Command families that can have their own policy in the
+// configuration. The "default" policy applies to every family
+// without a policy of its own.
+const (
+	FamilyDefault  = "default"
+	FamilyString   = "string"
+	FamilyHash     = "hash"
+	FamilyList     = "list"
+	FamilySet      = "set"
+	FamilyZSet     = "zset"
+	FamilyKeyspace = "keyspace"
+	FamilyScan     = "scan"
+)
+
+const (
+	_defaultBackoff    = 10 * time.Millisecond
+	_defaultMaxBackoff = time.Second
+)
+
+// Policy is the deadline and retry rules of a command family.
+// Read-only commands are retried up to MaxRetries times on
+// network errors and timeouts. Write commands are retried only
+// when they are listed in Idempotent.
+type Policy struct {
+	Timeout    time.Duration `yaml:"timeout"`
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	Idempotent []string      `yaml:"idempotent"`
+}
+
+var _commandFamilies = map[string]string{
+	"GET":              FamilyString,
+	"SET":              FamilyString,
+	"MGET":             FamilyString,
+	"MSET":             FamilyString,
+	"INCR":             FamilyString,
+	"INCRBY":           FamilyString,
+	"GETRANGE":         FamilyString,
+	"SETRANGE":         FamilyString,
+	"STRLEN":           FamilyString,
+	"GETEX":            FamilyString,
+	"GETDEL":           FamilyString,
+	"PFADD":            FamilyString,
+	"PFCOUNT":          FamilyString,
+	"PFMERGE":          FamilyString,
+	"SETBIT":           FamilyString,
+	"GETBIT":           FamilyString,
+	"BITCOUNT":         FamilyString,
+	"BITOP":            FamilyString,
+	"BITFIELD":         FamilyString,
+	"HGET":             FamilyHash,
+	"HSET":             FamilyHash,
+	"HDEL":             FamilyHash,
+	"HGETALL":          FamilyHash,
+	"HINCRBY":          FamilyHash,
+	"HKEYS":            FamilyHash,
+	"HLEN":             FamilyHash,
+	"HMGET":            FamilyHash,
+	"HMSET":            FamilyHash,
+	"HSETNX":           FamilyHash,
+	"HVALS":            FamilyHash,
+	"HEXPIRE":          FamilyHash,
+	"HPEXPIRE":         FamilyHash,
+	"HEXPIREAT":        FamilyHash,
+	"HTTL":             FamilyHash,
+	"HPERSIST":         FamilyHash,
+	"LINDEX":           FamilyList,
+	"LINSERT":          FamilyList,
+	"LLEN":             FamilyList,
+	"LPOP":             FamilyList,
+	"LPUSH":            FamilyList,
+	"LPUSHX":           FamilyList,
+	"LRANGE":           FamilyList,
+	"LREM":             FamilyList,
+	"LSET":             FamilyList,
+	"LTRIM":            FamilyList,
+	"RPOP":             FamilyList,
+	"RPUSH":            FamilyList,
+	"RPUSHX":           FamilyList,
+	"LMOVE":            FamilyList,
+	"BLMOVE":           FamilyList,
+	"BLPOP":            FamilyList,
+	"BRPOP":            FamilyList,
+	"SADD":             FamilySet,
+	"SCARD":            FamilySet,
+	"SDIFF":            FamilySet,
+	"SINTER":           FamilySet,
+	"SISMEMBER":        FamilySet,
+	"SMEMBERS":         FamilySet,
+	"SMOVE":            FamilySet,
+	"SPOP":             FamilySet,
+	"SRANDMEMBER":      FamilySet,
+	"SREM":             FamilySet,
+	"SUNION":           FamilySet,
+	"ZADD":             FamilyZSet,
+	"ZCARD":            FamilyZSet,
+	"ZCOUNT":           FamilyZSet,
+	"ZINCRBY":          FamilyZSet,
+	"ZINTERSTORE":      FamilyZSet,
+	"ZLEXCOUNT":        FamilyZSet,
+	"ZPOPMAX":          FamilyZSet,
+	"ZPOPMIN":          FamilyZSet,
+	"ZRANGE":           FamilyZSet,
+	"ZRANGEBYLEX":      FamilyZSet,
+	"ZRANGEBYSCORE":    FamilyZSet,
+	"ZRANK":            FamilyZSet,
+	"ZREM":             FamilyZSet,
+	"ZREMRANGEBYLEX":   FamilyZSet,
+	"ZREMRANGEBYRANK":  FamilyZSet,
+	"ZREMRANGEBYSCORE": FamilyZSet,
+	"ZREVRANGE":        FamilyZSet,
+	"ZREVRANGEBYLEX":   FamilyZSet,
+	"ZREVRANGEBYSCORE": FamilyZSet,
+	"ZREVRANK":         FamilyZSet,
+	"ZSCORE":           FamilyZSet,
+	"ZUNIONSTORE":      FamilyZSet,
+	"GEOADD":           FamilyZSet,
+	"GEOPOS":           FamilyZSet,
+	"GEODIST":          FamilyZSet,
+	"GEOSEARCH":        FamilyZSet,
+	"GEOSEARCHSTORE":   FamilyZSet,
+	"DEL":              FamilyKeyspace,
+	"EXISTS":           FamilyKeyspace,
+	"EXPIRE":           FamilyKeyspace,
+	"EXPIREAT":         FamilyKeyspace,
+	"TTL":              FamilyKeyspace,
+	"PTTL":             FamilyKeyspace,
+	"KEYS":             FamilyKeyspace,
+	"COPY":             FamilyKeyspace,
+	"SCAN":             FamilyScan,
+	"SSCAN":            FamilyScan,
+	"HSCAN":            FamilyScan,
+	"ZSCAN":            FamilyScan,
+}
+
+// CommandFamily returns the family of the command or
+// FamilyDefault for unknown commands.
+func CommandFamily(command string) string {
+	if family, ok := _commandFamilies[strings.ToUpper(command)]; ok {
+		return family
+	}
+	return FamilyDefault
+}
+
+type policies struct {
+	byFamily map[string]Policy
+	fallback Policy
+}
+
+var _families = []string{
+	FamilyDefault, FamilyString, FamilyHash, FamilyList,
+	FamilySet, FamilyZSet, FamilyKeyspace, FamilyScan,
+}
+
+func newPolicies(cfg *Config) (*policies, error) {
+	for family := range cfg.Policies {
+		if !slices.Contains(_families, family) {
+			return nil, fmt.Errorf("неизвестное семейство команд %q в policies", family)
+		}
+	}
+
+	p := &policies{
+		byFamily: make(map[string]Policy, len(cfg.Policies)),
+		fallback: Policy{Timeout: cfg.ReadTimeout},
+	}
+	if policy, ok := cfg.Policies[FamilyDefault]; ok {
+		p.fallback = policy.withDefaults(cfg.ReadTimeout)
+	}
+	for family, policy := range cfg.Policies {
+		p.byFamily[family] = policy.withDefaults(p.fallback.Timeout)
+	}
+	return p, nil
+}
+
+func (p *policies) lookup(cmd rueidis.Completed) Policy {
+	if p == nil {
+		return Policy{}
+	}
+	commands := cmd.Commands()
+	if len(commands) == 0 {
+		return p.fallback
+	}
+	if policy, ok := p.byFamily[CommandFamily(commands[0])]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+func (p *policies) defaults() Policy {
+	if p == nil {
+		return Policy{}
+	}
+	return p.fallback
+}
+
+func (p Policy) withDefaults(timeout time.Duration) Policy {
+	if p.Timeout == 0 {
+		p.Timeout = timeout
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = _defaultBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = _defaultMaxBackoff
+	}
+	// The slice is shared with the config, so it is copied.
+	idempotent := make([]string, len(p.Idempotent))
+	for i, name := range p.Idempotent {
+		idempotent[i] = strings.ToUpper(name)
+	}
+	p.Idempotent = idempotent
+	return p
+}
+
+func (p Policy) retryable(cmd rueidis.Completed) bool {
+	if p.MaxRetries <= 0 {
+		return false
+	}
+	if cmd.IsReadOnly() {
+		return true
+	}
+	commands := cmd.Commands()
+	if len(commands) == 0 {
+		return false
+	}
+	for _, name := range p.Idempotent {
+		if name == strings.ToUpper(commands[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before attempt+1: it grows
+// exponentially from Backoff up to MaxBackoff with a random
+// jitter within the upper half of the interval.
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.Backoff << min(attempt, 30)
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (r *Redis) do(ctx context.Context, cmd rueidis.Completed) reply {
+	return r.doOn(ctx, r.conn, cmd)
+}
+
+// doOn runs cmd on client under the policy of its family:
+// every attempt is limited by the policy timeout and retryable
+// commands are repeated on network errors. Writes are followed by
+// WAIT when the durability of ctx or of the rules applies.
+func (r *Redis) doOn(ctx context.Context, client rueidis.Client, cmd rueidis.Completed) (result reply) {
+	r.observeKey(cmd.Commands())
+	if recorded := r.recorder.capture(cmd); recorded != nil {
+		defer func(startTime time.Time) {
+			r.recorder.record(recorded, startTime, result)
+		}(time.Now())
+	}
+	policy := r.policies.lookup(cmd)
+	retry := policy.retryable(cmd)
+	if retry {
+		cmd = cmd.Pin()
+	}
+	durability, durable := r.durability(ctx, rueidis.Commands{cmd})
+
+	r.addInFlight(1)
+	defer r.addInFlight(-1)
+
+	for attempt := 0; ; attempt++ {
+		if err := r.inject(ctx, cmd.Commands()[0]); err != nil {
+			result = reply{err: err}
+		} else if durable {
+			result = r.doDurableOn(ctx, client, durability, cmd, policy.Timeout)
+		} else {
+			result = reply{result: doWithTimeout(ctx, client, cmd, policy.Timeout)}
+		}
+		// The write of an under-replicated command is applied, a
+		// retry doesn't help.
+		if !retry || attempt >= policy.MaxRetries || result.NonRedisError() == nil || errors.Is(result.err, ErrUnderReplicated) || ctx.Err() != nil {
+			return result
+		}
+		if !sleep(ctx, policy.backoff(attempt)) {
+			return result
+		}
+	}
+}
+
+// withTimeout limits ctx by the default policy timeout. It is
+// used for pipelines and cached commands, where a single family
+// can't be picked.
+func (r *Redis) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := r.policies.defaults().Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+func doWithTimeout(ctx context.Context, client rueidis.Client, cmd rueidis.Completed, timeout time.Duration) rueidis.RedisResult {
+	if timeout > 0 && !cmd.IsBlock() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return client.Do(ctx, cmd)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,274 @@
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultVisibilityTimeout = 30 * time.Second
+	_defaultPollTimeout       = 5 * time.Second
+	_defaultReapInterval      = 10 * time.Second
+	_defaultReapLimit         = 100
+	_defaultQueueErrorWait    = time.Second
+)
+
+// _reapScript gives a lease to items in the processing list that
+// have none, e.g. when the worker died right after BLMOVE, and
+// moves items with an expired lease back to the pending list.
+var _reapScript = NewScript("queue_reap", `
+local now = tonumber(ARGV[1])
+local visibility = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+for _, item in ipairs(redis.call('LRANGE', KEYS[2], -limit, -1)) do
+	redis.call('ZADD', KEYS[3], 'NX', now + visibility, item)
+end
+
+local requeued = 0
+for _, item in ipairs(redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now, 'LIMIT', 0, limit)) do
+	redis.call('ZREM', KEYS[3], item)
+	if redis.call('LREM', KEYS[2], -1, item) > 0 then
+		redis.call('RPUSH', KEYS[1], item)
+		requeued = requeued + 1
+	end
+end
+return requeued
+`)
+
+// _requeueScript moves an item from the processing list back to
+// the head of the pending list.
+var _requeueScript = NewScript("queue_requeue", `
+redis.call('ZREM', KEYS[3], ARGV[1])
+if redis.call('LREM', KEYS[2], -1, ARGV[1]) > 0 then
+	redis.call('RPUSH', KEYS[1], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+type QueueConfig struct {
+	Name              string        `yaml:"name"`
+	Workers           int           `yaml:"workers"`
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+	PollTimeout       time.Duration `yaml:"poll_timeout"`
+	ReapInterval      time.Duration `yaml:"reap_interval"`
+	ReapLimit         int           `yaml:"reap_limit"`
+}
+
+// QueueHandler processes an item. The item is acknowledged when
+// the handler returns nil and requeued otherwise.
+type QueueHandler func(ctx context.Context, item string) error
+
+// Queue is a reliable queue on redis lists. Received items are
+// moved atomically to a processing list and leased for
+// VisibilityTimeout, items that are not acknowledged in time are
+// requeued by the reaper, so every item is delivered at least
+// once. Items are matched by value, push unique items (e.g. with
+// an ID inside).
+//
+// Queue implements service.ServiceWithDown: Workers goroutines
+// pass items to the handler and the reaper runs between Up and
+// Down. A queue without handler only runs the reaper.
+type Queue struct {
+	redis      *Redis
+	cfg        QueueConfig
+	handler    QueueHandler
+	pending    string
+	processing string
+	leases     string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewQueue(r *Redis, cfg QueueConfig, handler QueueHandler) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = _defaultVisibilityTimeout
+	}
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = _defaultPollTimeout
+	}
+	if cfg.ReapInterval <= 0 {
+		cfg.ReapInterval = _defaultReapInterval
+	}
+	if cfg.ReapLimit <= 0 {
+		cfg.ReapLimit = _defaultReapLimit
+	}
+
+	// The hash tag keeps all keys of the queue in one cluster slot.
+	prefix := "queue:{" + cfg.Name + "}:"
+	return &Queue{
+		redis:      r,
+		cfg:        cfg,
+		handler:    handler,
+		pending:    prefix + "pending",
+		processing: prefix + "processing",
+		leases:     prefix + "leases",
+	}
+}
+
+// Push adds items to the tail of the queue.
+func (q *Queue) Push(ctx context.Context, items ...string) error {
+	_, err := q.redis.LPush(ctx, q.pending, items...)
+	return err
+}
+
+// Receive waits up to PollTimeout for an item and leases it for
+// VisibilityTimeout. The error is rueidis nil when the queue is
+// empty.
+func (q *Queue) Receive(ctx context.Context) (string, error) {
+	item, err := q.redis.BLMove(ctx, q.pending, q.processing, Right, Left, q.cfg.PollTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(q.cfg.VisibilityTimeout).UnixMilli()
+	if _, err = q.redis.ZAdd(ctx, q.leases, float64(deadline), item); err != nil {
+		// The reaper leases the item, it isn't lost.
+		return "", err
+	}
+	return item, nil
+}
+
+// Ack removes a processed item from the queue.
+func (q *Queue) Ack(ctx context.Context, item string) error {
+	return HasError(q.redis.DoMulti(ctx,
+		q.redis.conn.B().Lrem().Key(q.processing).Count(-1).Element(item).Build(),
+		q.redis.conn.B().Zrem().Key(q.leases).Member(item).Build(),
+	))
+}
+
+// Nack returns a received item to the head of the queue.
+func (q *Queue) Nack(ctx context.Context, item string) error {
+	return q.redis.Eval(ctx, _requeueScript, q.keys(), []string{item}).Error()
+}
+
+// Reap requeues items with an expired lease and returns their
+// number.
+func (q *Queue) Reap(ctx context.Context) (int64, error) {
+	args := []string{
+		strconv.FormatInt(time.Now().UnixMilli(), 10),
+		strconv.FormatInt(q.cfg.VisibilityTimeout.Milliseconds(), 10),
+		strconv.Itoa(q.cfg.ReapLimit),
+	}
+	return q.redis.Eval(ctx, _reapScript, q.keys(), args).AsInt64()
+}
+
+// Len returns the number of pending and processing items.
+func (q *Queue) Len(ctx context.Context) (pending, processing int64, err error) {
+	results := q.redis.DoMulti(ctx,
+		q.redis.conn.B().Llen().Key(q.pending).Build(),
+		q.redis.conn.B().Llen().Key(q.processing).Build(),
+	)
+	if err = HasError(results); err != nil {
+		return 0, 0, err
+	}
+	pending, _ = results[0].AsInt64()
+	processing, _ = results[1].AsInt64()
+	return pending, processing, nil
+}
+
+func (q *Queue) Up(ctx context.Context) error {
+	q.mu.Lock()
+	ctx, q.cancel = context.WithCancel(ctx)
+	q.done = make(chan struct{})
+	q.mu.Unlock()
+	defer close(q.done)
+
+	var wg sync.WaitGroup
+	if q.handler != nil {
+		for range q.cfg.Workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				q.work(ctx)
+			}()
+		}
+	}
+	q.reap(ctx)
+	wg.Wait()
+
+	return nil
+}
+
+func (q *Queue) Down(ctx context.Context) error {
+	q.mu.Lock()
+	cancel, done := q.cancel, q.done
+	q.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		item, err := q.Receive(ctx)
+		if rueidis.IsRedisNil(err) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Ошибка получения элемента очереди", "error", err, "queue", q.cfg.Name)
+			sleep(ctx, _defaultQueueErrorWait)
+			continue
+		}
+
+		// A handled item is acknowledged even on shutdown, otherwise
+		// it would be delivered again.
+		ackCtx := context.WithoutCancel(ctx)
+		if err = q.handler(ctx, item); err != nil {
+			slog.Error("Ошибка обработки элемента очереди", "error", err, "queue", q.cfg.Name)
+			err = q.Nack(ackCtx, item)
+		} else {
+			err = q.Ack(ackCtx, item)
+		}
+		if err != nil {
+			slog.Error("Ошибка подтверждения элемента очереди", "error", err, "queue", q.cfg.Name)
+		}
+	}
+}
+
+func (q *Queue) reap(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requeued, err := q.Reap(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Ошибка возврата просроченных элементов очереди", "error", err, "queue", q.cfg.Name)
+			}
+			if requeued > 0 {
+				slog.Warn("Просроченные элементы возвращены в очередь", "queue", q.cfg.Name, "count", requeued)
+			}
+		}
+	}
+}
+
+func (q *Queue) keys() []string {
+	return []string{q.pending, q.processing, q.leases}
+}
@@ -0,0 +1,367 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultRecorderMaxSize      = 100 << 20
+	_defaultRecorderMaxFiles     = 5
+	_defaultRecorderMaxArgLength = 256
+	_defaultRecorderBuffer       = 10000
+	_recorderFlushInterval       = time.Second
+	_redacted                    = "?"
+)
+
+// Commands whose arguments are always redacted.
+var _secretCommands = []string{"AUTH", "HELLO", "MIGRATE"}
+
+// RecorderConfig configures a Recorder. SampleRate is the share of
+// commands recorded. Commands matching Redact, globs of command
+// names like "SET" or "H*", keep only their first argument,
+// usually the key, the other arguments and the result are replaced
+// with "?".
+// Arguments and results longer than MaxArgLength are truncated,
+// writes with redacted or truncated arguments are not replayed.
+// The file is rotated when it exceeds MaxSize bytes, MaxFiles
+// rotated files are kept as Path.1, Path.2 and so on.
+type RecorderConfig struct {
+	Path         string   `yaml:"path"`
+	MaxSize      int64    `yaml:"max_size"`
+	MaxFiles     int      `yaml:"max_files"`
+	SampleRate   float64  `yaml:"sample_rate"`
+	Redact       []string `yaml:"redact"`
+	MaxArgLength int      `yaml:"max_arg_length"`
+	Buffer       int      `yaml:"buffer"`
+}
+
+// Recording is a line of a recording file. Duration is in
+// nanoseconds. With Encoding "base64" all arguments of Command are
+// base64, it is used for arguments that aren't valid UTF-8.
+// Partial is set for writes with redacted or truncated arguments.
+type Recording struct {
+	Time     time.Time     `json:"time"`
+	Command  []string      `json:"command"`
+	Encoding string        `json:"encoding,omitempty"`
+	Partial  bool          `json:"partial,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Result   string        `json:"result,omitempty"`
+	Error    string        `json:"error,omitempty"`
+
+	redacted bool
+}
+
+// Args returns the arguments of Command decoded.
+func (rec Recording) Args() ([]string, error) {
+	if rec.Encoding != _encodingBase64 {
+		return rec.Command, nil
+	}
+	args := make([]string, len(rec.Command))
+	for idx, arg := range rec.Command {
+		raw, err := base64.StdEncoding.DecodeString(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[idx] = string(raw)
+	}
+	return args, nil
+}
+
+// Recorder writes the commands sent through a Redis to a rotating
+// NDJSON file, see Redis.WithRecorder. Commands are written in
+// the background, when the buffer is full they are dropped.
+// Recorder implements service.ServiceWithDown, the file is
+// written between Up and Down.
+type Recorder struct {
+	cfg     RecorderConfig
+	entries chan Recording
+	dropped atomic.Int64
+
+	file *os.File
+	size int64
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRecorder(cfg RecorderConfig) *Recorder {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = _defaultRecorderMaxSize
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = _defaultRecorderMaxFiles
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 1
+	}
+	if cfg.MaxArgLength <= 0 {
+		cfg.MaxArgLength = _defaultRecorderMaxArgLength
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = _defaultRecorderBuffer
+	}
+
+	return &Recorder{
+		cfg:     cfg,
+		entries: make(chan Recording, cfg.Buffer),
+	}
+}
+
+// WithRecorder records the commands, cached commands and
+// pipelines sent through r. Dedicated connections, pub/sub and
+// WAIT are not recorded.
+func (r *Redis) WithRecorder(recorder *Recorder) *Redis {
+	r.recorder = recorder
+	return r
+}
+
+// Dropped returns the number of commands dropped because the
+// buffer was full.
+func (rec *Recorder) Dropped() int64 {
+	return rec.dropped.Load()
+}
+
+// RecordingFiles returns the recording files of path from the
+// oldest to the newest.
+func RecordingFiles(path string, maxFiles int) []string {
+	if maxFiles <= 0 {
+		maxFiles = _defaultRecorderMaxFiles
+	}
+
+	var files []string
+	for idx := maxFiles; idx > 0; idx-- {
+		if _, err := os.Stat(rotated(path, idx)); err == nil {
+			files = append(files, rotated(path, idx))
+		}
+	}
+	return append(files, path)
+}
+
+// capture copies the arguments of a sampled command, since
+// commands are recycled once they are sent. It returns nil when
+// the command is not recorded.
+func (rec *Recorder) capture(cmd rueidis.Completed) *Recording {
+	if rec == nil || rand.Float64() >= rec.cfg.SampleRate {
+		return nil
+	}
+	commands := cmd.Commands()
+
+	entry := &Recording{Command: make([]string, len(commands)), redacted: rec.redacted(commands[0])}
+
+	// A redacted command keeps its first argument unless it is
+	// the only one, e.g. the password of AUTH.
+	keep := len(commands)
+	if entry.redacted {
+		keep = min(2, len(commands)-1)
+	}
+
+	altered := keep < len(commands)
+	for idx, arg := range commands {
+		if idx >= keep {
+			arg = _redacted
+		}
+		entry.Command[idx] = truncate(arg, rec.cfg.MaxArgLength)
+		altered = altered || len(arg) > rec.cfg.MaxArgLength
+	}
+	entry.Partial = altered && cmd.IsWrite()
+
+	if slices.ContainsFunc(entry.Command, func(arg string) bool { return !utf8.ValidString(arg) }) {
+		entry.Encoding = _encodingBase64
+		for idx, arg := range entry.Command {
+			entry.Command[idx] = base64.StdEncoding.EncodeToString([]byte(arg))
+		}
+	}
+	return entry
+}
+
+func (rec *Recorder) redacted(command string) bool {
+	for _, secret := range _secretCommands {
+		if command == secret {
+			return true
+		}
+	}
+	for _, pattern := range rec.cfg.Redact {
+		if matchPattern(pattern, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// record queues the result of a captured command.
+func (rec *Recorder) record(captured *Recording, startTime time.Time, result reply) {
+	if captured == nil {
+		return
+	}
+
+	entry := *captured
+	entry.Time, entry.Duration = startTime, time.Since(startTime)
+	if err := result.Error(); err != nil && !rueidis.IsRedisNil(err) {
+		entry.Error = truncate(err.Error(), rec.cfg.MaxArgLength)
+	} else if entry.redacted {
+		entry.Result = _redacted
+	} else if msg, err := result.ToMessage(); err == nil {
+		entry.Result = truncate(msg.String(), rec.cfg.MaxArgLength)
+	}
+
+	select {
+	case rec.entries <- entry:
+	default:
+		rec.dropped.Add(1)
+	}
+}
+
+// captureMulti captures the commands of a pipeline.
+func (rec *Recorder) captureMulti(n int, cmd func(idx int) rueidis.Completed) []*Recording {
+	if rec == nil {
+		return nil
+	}
+
+	captured := make([]*Recording, n)
+	for idx := range n {
+		captured[idx] = rec.capture(cmd(idx))
+	}
+	return captured
+}
+
+func (rec *Recorder) recordMulti(captured []*Recording, startTime time.Time, results []rueidis.RedisResult) {
+	for idx := range captured {
+		rec.record(captured[idx], startTime, reply{result: results[idx]})
+	}
+}
+
+func (rec *Recorder) Up(ctx context.Context) error {
+	rec.mu.Lock()
+	ctx, rec.cancel = context.WithCancel(ctx)
+	rec.done = make(chan struct{})
+	rec.mu.Unlock()
+	defer close(rec.done)
+
+	if err := rec.open(); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(rec.file)
+	defer func() {
+		if err := w.Flush(); err != nil {
+			slog.Error("Ошибка записи команд redis", "error", err, "path", rec.cfg.Path)
+		}
+		rec.file.Close()
+	}()
+
+	ticker := time.NewTicker(_recorderFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The buffered commands are written before stopping.
+			for {
+				select {
+				case entry := <-rec.entries:
+					rec.write(w, entry)
+				default:
+					return nil
+				}
+			}
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				slog.Error("Ошибка записи команд redis", "error", err, "path", rec.cfg.Path)
+			}
+		case entry := <-rec.entries:
+			rec.write(w, entry)
+		}
+	}
+}
+
+func (rec *Recorder) Down(ctx context.Context) error {
+	rec.mu.Lock()
+	cancel, done := rec.cancel, rec.done
+	rec.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rec *Recorder) write(w *bufio.Writer, entry Recording) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("Ошибка сериализации команды redis", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	if rec.size > 0 && rec.size+int64(len(line)) > rec.cfg.MaxSize {
+		if err := w.Flush(); err != nil {
+			slog.Error("Ошибка записи команд redis", "error", err, "path", rec.cfg.Path)
+		}
+		if err := rec.rotate(); err != nil {
+			slog.Error("Ошибка ротации файла команд redis", "error", err, "path", rec.cfg.Path)
+		}
+		w.Reset(rec.file)
+	}
+
+	n, err := w.Write(line)
+	rec.size += int64(n)
+	if err != nil {
+		slog.Error("Ошибка записи команд redis", "error", err, "path", rec.cfg.Path)
+	}
+}
+
+func (rec *Recorder) open() error {
+	file, err := os.OpenFile(rec.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("Ошибка открытия файла команд redis: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rec.file, rec.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts Path.N to Path.N+1 dropping the oldest file and
+// starts a new Path.
+func (rec *Recorder) rotate() error {
+	errAll := rec.file.Close()
+	for idx := rec.cfg.MaxFiles - 1; idx > 0; idx-- {
+		if err := os.Rename(rotated(rec.cfg.Path, idx), rotated(rec.cfg.Path, idx+1)); err != nil && !os.IsNotExist(err) {
+			errAll = errors.Join(errAll, err)
+		}
+	}
+	errAll = errors.Join(errAll, os.Rename(rec.cfg.Path, rotated(rec.cfg.Path, 1)))
+
+	// The file is reopened even if the rotation failed.
+	return errors.Join(errAll, rec.open())
+}
+
+func rotated(path string, idx int) string {
+	return path + "." + strconv.Itoa(idx)
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderRedact(t *testing.T) {
+	client := newFakeClient(t, func([]string) string { return "$6\r\nsecret\r\n" })
+	rec := NewRecorder(RecorderConfig{Redact: []string{"H*"}})
+
+	tests := []struct {
+		name     string
+		command  []string
+		want     []string
+		redacted bool
+	}{
+		{"plain", []string{"GET", "k"}, []string{"GET", "k"}, false},
+		{"redacted", []string{"HGET", "k", "f"}, []string{"HGET", "k", "?"}, true},
+		{"secret", []string{"AUTH", "password"}, []string{"AUTH", "?"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := client.B().Arbitrary(tt.command...).Build()
+			captured := rec.capture(cmd)
+			rec.record(captured, time.Now(), reply{result: client.Do(context.Background(), cmd)})
+
+			entry := <-rec.entries
+			if !slices.Equal(entry.Command, tt.want) {
+				t.Errorf("command = %q, want %q", entry.Command, tt.want)
+			}
+			if got := entry.Result == _redacted; got != tt.redacted {
+				t.Errorf("result = %q, redacted %v", entry.Result, tt.redacted)
+			}
+			if !tt.redacted && !strings.Contains(entry.Result, "secret") {
+				t.Errorf("result = %q, want the reply", entry.Result)
+			}
+		})
+	}
+}
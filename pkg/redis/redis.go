@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"skeleton/pkg/hostname"
 	"sync"
@@ -17,29 +18,43 @@ type metrics interface {
 }
 
 type Config struct {
-	Name                 string        `env:"REDIS_NAME" yaml:"name"`
-	Hosts                []string      `env:"REDIS_HOST" yaml:"host"`
-	Username             string        `env:"REDIS_USERNAME" yaml:"username"`
-	Password             string        `env:"REDIS_PASSWORD" yaml:"password"`
-	ClientName           string        `env:"REDIS_APP" yaml:"app"`
-	TTL                  time.Duration `env:"REDIS_TTL" yaml:"ttl"`
-	AlwaysPipelining     bool          `env:"REDIS_ALWAYS_PIPELINING" yaml:"always_pipelining" default:"true" env-default:"true"`
-	PipelineMultiplex    int           `env:"REDIS_PIPELINE_MULTIPLEX" yaml:"pipeline_multiplex" default:"1" env-default:"1"`
-	RedisSentinelPrimary string        `env:"REDIS_SENTINEL_PRIMARY" yaml:"sentinel_primary" default:"" env-default:""`
-	DisableCache         bool          `env:"REDIS_DISABLE_CACHE" yaml:"disable_cache"`
-	ReplicaOnly          bool          `env:"REDIS_REPLICA_ONLY" yaml:"replica_only" default:"false" env-default:"false"`
-	DialTimeout          time.Duration `env:"REDIS_DIAL_TIMEOUT" yaml:"dial_timeout" default:"10s" env-default:"10s"`
-	ReadTimeout          time.Duration `env:"REDIS_READ_TIMEOUT" yaml:"read_timeout" default:"1s" env-default:"1s"`
-	DisableRetry         bool          `env:"REDIS_DISABLE_RETRY" yaml:"disable_retry" default:"false" env-default:"false"`
-	ForceSingleClient    bool          `env:"REDIS_FORCE_SINGLE_CLIENT" yaml:"force_single_client" default:"false" env-default:"false"`
-	MaxFlushDelay        time.Duration `env:"REDIS_MAX_FLASH_DELAY" yaml:"max_flush_delay" env-default:"10ms"`
+	Name                 string            `env:"REDIS_NAME" yaml:"name"`
+	Hosts                []string          `env:"REDIS_HOST" yaml:"host"`
+	Username             string            `env:"REDIS_USERNAME" yaml:"username"`
+	Password             string            `env:"REDIS_PASSWORD" yaml:"password"`
+	ClientName           string            `env:"REDIS_APP" yaml:"app"`
+	TTL                  time.Duration     `env:"REDIS_TTL" yaml:"ttl"`
+	AlwaysPipelining     bool              `env:"REDIS_ALWAYS_PIPELINING" yaml:"always_pipelining" default:"true" env-default:"true"`
+	PipelineMultiplex    int               `env:"REDIS_PIPELINE_MULTIPLEX" yaml:"pipeline_multiplex" default:"1" env-default:"1"`
+	RedisSentinelPrimary string            `env:"REDIS_SENTINEL_PRIMARY" yaml:"sentinel_primary" default:"" env-default:""`
+	DisableCache         bool              `env:"REDIS_DISABLE_CACHE" yaml:"disable_cache"`
+	ReplicaOnly          bool              `env:"REDIS_REPLICA_ONLY" yaml:"replica_only" default:"false" env-default:"false"`
+	DialTimeout          time.Duration     `env:"REDIS_DIAL_TIMEOUT" yaml:"dial_timeout" default:"10s" env-default:"10s"`
+	ReadTimeout          time.Duration     `env:"REDIS_READ_TIMEOUT" yaml:"read_timeout" default:"1s" env-default:"1s"`
+	DisableRetry         bool              `env:"REDIS_DISABLE_RETRY" yaml:"disable_retry" default:"false" env-default:"false"`
+	ForceSingleClient    bool              `env:"REDIS_FORCE_SINGLE_CLIENT" yaml:"force_single_client" default:"false" env-default:"false"`
+	MaxFlushDelay        time.Duration     `env:"REDIS_MAX_FLASH_DELAY" yaml:"max_flush_delay" env-default:"10ms"`
+	Policies             map[string]Policy `yaml:"policies"`
+	HotKeys              HotKeysConfig     `yaml:"hot_keys"`
+	Analyzer             AnalyzerConfig    `yaml:"analyzer"`
+	Events               EventBusConfig    `yaml:"events"`
+	TTLRules             []TTLRule         `yaml:"ttl_rules"`
+	Durability           []DurabilityRule  `yaml:"durability"`
 }
 
 type Redis struct {
-	connectionName string
-	conn           rueidis.Client
-	ttl            time.Duration
-	metrics        metrics
+	connectionName  string
+	conn            rueidis.Client
+	ttl             time.Duration
+	metrics         metrics
+	policies        *policies
+	stats           *stats
+	hotKeys         *HotKeys
+	ttlRules        ttlRules
+	hashFieldTTL    capability
+	durabilityRules []DurabilityRule
+	chaos           faultInjector
+	recorder        *Recorder
 }
 
 func New(cfg *Config, metrics metrics) (*Redis, error) {
@@ -50,6 +65,27 @@ func New(cfg *Config, metrics metrics) (*Redis, error) {
 		cfg.ClientName = hostname.GetHostName()
 	}
 
+	familyPolicies, err := newPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Retries are made by doOn under the policies, the retries of
+	// rueidis would repeat reads twice and retry writes.
+	disableRetry := cfg.DisableRetry || len(cfg.Policies) > 0
+
+	r := &Redis{
+		connectionName:  cfg.Name,
+		metrics:         metrics,
+		ttl:             cfg.TTL,
+		policies:        familyPolicies,
+		stats:           newStats(metrics),
+		ttlRules:        newTTLRules(cfg),
+		durabilityRules: cfg.Durability,
+	}
+	if cfg.HotKeys.Enabled {
+		r.hotKeys = NewHotKeys(cfg.HotKeys)
+	}
+
 	if cfg.RedisSentinelPrimary != "" {
 		conn, err = rueidis.NewClient(rueidis.ClientOption{
 			Password:          cfg.Password,
@@ -58,9 +94,10 @@ func New(cfg *Config, metrics metrics) (*Redis, error) {
 			PipelineMultiplex: cfg.PipelineMultiplex,
 			InitAddress:       cfg.Hosts,
 			DisableCache:      cfg.DisableCache,
-			DisableRetry:      cfg.DisableRetry,
+			DisableRetry:      disableRetry,
 			ReplicaOnly:       cfg.ReplicaOnly,
 			MaxFlushDelay:     cfg.MaxFlushDelay,
+			DialFn:            r.dial,
 			Dialer: net.Dialer{
 				Timeout: cfg.DialTimeout,
 			},
@@ -79,37 +116,34 @@ func New(cfg *Config, metrics metrics) (*Redis, error) {
 			AlwaysPipelining:  cfg.AlwaysPipelining,
 			PipelineMultiplex: cfg.PipelineMultiplex,
 			DisableCache:      cfg.DisableCache,
+			DisableRetry:      disableRetry,
 			ReplicaOnly:       cfg.ReplicaOnly,
 			ForceSingleClient: cfg.ForceSingleClient,
+			DialFn:            r.dial,
 		})
 	}
 	if err != nil {
 		return nil, fmt.Errorf("Ошибка при инициализации клиента rueidis %w", err)
 	}
 
-	return &Redis{
-		conn:    conn,
-		metrics: metrics,
-		ttl:     cfg.TTL,
-	}, nil
-}
-
-func (r *Redis) writeTimingAndCounter(startTime time.Time, query string, success bool) {
-	if r.metrics != nil {
-		r.metrics.WriteTimingAndCounter(startTime, query, success)
+	r.conn = conn
+	if err := r.loadTTLScripts(context.Background()); err != nil {
+		slog.Error("Ошибка загрузки скриптов TTL", "error", err)
 	}
+
+	return r, nil
 }
 
 func (r *Redis) Exists(ctx context.Context, key ...string) (bool, error) {
 	start := time.Now()
-	asBool, err := r.conn.Do(ctx, r.conn.B().Exists().Key(key...).Build()).AsBool()
+	asBool, err := r.do(ctx, r.conn.B().Exists().Key(key...).Build()).AsBool()
 	r.writeTimingAndCounter(start, "redis_exist", err == nil)
 	return asBool, err
 }
 
 func (r *Redis) Get(ctx context.Context, key string) (string, error) {
 	start := time.Now()
-	value, err := r.conn.Do(ctx, r.conn.B().Get().Key(key).Build()).ToString()
+	value, err := r.do(ctx, r.conn.B().Get().Key(key).Build()).ToString()
 	r.writeTimingAndCounter(start, "redis_get", err == nil)
 
 	return value, err
@@ -117,27 +151,37 @@ func (r *Redis) Get(ctx context.Context, key string) (string, error) {
 
 func (r *Redis) GetMulti(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
 	start := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Mget().Key(keys...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Mget().Key(keys...).Build()).ToArray()
 	r.writeTimingAndCounter(start, "redis_get_multi", err == nil)
 
 	return result, err
 }
 
+// SetCompleted builds SET key value. Without ttl the TTL rule of
+// the key applies: the builder returns EVALSHA of the rule script
+// instead of SET. The script is loaded by New, pipelines of Redis
+// resend the command as EVAL when the server lost it since, e.g.
+// after a restart or SCRIPT FLUSH.
 func (r *Redis) SetCompleted(ctx context.Context, key, value string, ttl time.Duration) rueidis.Completed {
 	if ttl > 0 {
 		return r.conn.B().Set().Key(key).Value(value).Ex(ttl).Build()
+	} else if rule, ok := r.ttlRules.lookup(key); ok {
+		return r.ttlSetCompleted(rule, key, value)
 	} else {
 		return r.conn.B().Set().Key(key).Value(value).Build()
 	}
 }
 
+// Set sets key to value. Without ttl the TTL rule of the key
+// applies.
 func (r *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) error {
 	start := time.Now()
-	b := r.conn.B().Set().Key(key).Value(value)
-	if ttl > 0 {
-		b.Ex(ttl)
+	var err error
+	if rule, ok := r.ttlRules.lookup(key); ok && ttl <= 0 {
+		err = r.ttlSet(ctx, rule, key, value)
+	} else {
+		err = r.do(ctx, r.SetCompleted(ctx, key, value, ttl)).Error()
 	}
-	err := r.conn.Do(ctx, b.Build()).Error()
 	r.writeTimingAndCounter(start, "redis_set", err == nil)
 
 	return err
@@ -145,7 +189,7 @@ func (r *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) e
 
 func (r *Redis) Del(ctx context.Context, key string) (int64, error) {
 	start := time.Now()
-	cnt, err := r.conn.Do(ctx, r.conn.B().Del().Key(key).Build()).ToInt64()
+	cnt, err := r.do(ctx, r.conn.B().Del().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_del", err == nil)
 
 	return cnt, err
@@ -157,7 +201,7 @@ func (r *Redis) DelCompleted(key string) rueidis.Completed {
 
 func (r *Redis) DelMulti(ctx context.Context, keys ...string) (int64, error) {
 	start := time.Now()
-	cnt, err := r.conn.Do(ctx, r.conn.B().Del().Key(keys...).Build()).ToInt64()
+	cnt, err := r.do(ctx, r.conn.B().Del().Key(keys...).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_del_multi", err == nil)
 
 	return cnt, err
@@ -165,7 +209,7 @@ func (r *Redis) DelMulti(ctx context.Context, keys ...string) (int64, error) {
 
 func (r *Redis) Expire(ctx context.Context, key string, ttl time.Duration) error {
 	start := time.Now()
-	_, err := r.conn.Do(ctx, r.conn.B().Expire().Key(key).Seconds(int64(ttl/time.Second)).Build()).ToAny()
+	_, err := r.do(ctx, r.conn.B().Expire().Key(key).Seconds(int64(ttl/time.Second)).Build()).ToAny()
 	r.writeTimingAndCounter(start, "redis_expire", err == nil)
 
 	return err
@@ -173,7 +217,7 @@ func (r *Redis) Expire(ctx context.Context, key string, ttl time.Duration) error
 
 func (r *Redis) ExpireAt(ctx context.Context, key string, at time.Time) error {
 	start := time.Now()
-	err := r.conn.Do(ctx, r.conn.B().Expireat().Key(key).Timestamp(at.Unix()).Build()).Error()
+	err := r.do(ctx, r.conn.B().Expireat().Key(key).Timestamp(at.Unix()).Build()).Error()
 	r.writeTimingAndCounter(start, "redis_expire_at", err == nil)
 
 	return err
@@ -185,7 +229,7 @@ func (r *Redis) ExpireAtCompleted(key string, at time.Time) rueidis.Completed {
 
 func (r *Redis) TTL(ctx context.Context, key string) (int64, error) {
 	start := time.Now()
-	value, err := r.conn.Do(ctx, r.conn.B().Ttl().Key(key).Build()).ToInt64()
+	value, err := r.do(ctx, r.conn.B().Ttl().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_ttl", err == nil)
 
 	return value, err
@@ -193,7 +237,7 @@ func (r *Redis) TTL(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) PTTL(ctx context.Context, key string) (int64, error) {
 	start := time.Now()
-	value, err := r.conn.Do(ctx, r.conn.B().Pttl().Key(key).Build()).ToInt64()
+	value, err := r.do(ctx, r.conn.B().Pttl().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_pttl", err == nil)
 
 	return value, err
@@ -201,7 +245,7 @@ func (r *Redis) PTTL(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) Incr(ctx context.Context, key string) (int64, error) {
 	start := time.Now()
-	value, err := r.conn.Do(ctx, r.conn.B().Incr().Key(key).Build()).ToInt64()
+	value, err := r.do(ctx, r.conn.B().Incr().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_incr", err == nil)
 
 	return value, err
@@ -209,7 +253,7 @@ func (r *Redis) Incr(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
 	start := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Incrby().Key(key).Increment(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Incrby().Key(key).Increment(value).Build()).ToInt64()
 	r.writeTimingAndCounter(start, "redis_incr_by", err == nil)
 
 	return result, err
@@ -217,7 +261,7 @@ func (r *Redis) IncrBy(ctx context.Context, key string, value int64) (int64, err
 
 func (r *Redis) GetRange(ctx context.Context, key string, start, end int64) (string, error) {
 	startTime := time.Now()
-	value, err := r.conn.Do(ctx, r.conn.B().Getrange().Key(key).Start(start).End(end).Build()).ToString()
+	value, err := r.do(ctx, r.conn.B().Getrange().Key(key).Start(start).End(end).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_get_range", err == nil)
 
 	return value, err
@@ -225,7 +269,7 @@ func (r *Redis) GetRange(ctx context.Context, key string, start, end int64) (str
 
 func (r *Redis) SetRange(ctx context.Context, key string, offset int64, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Setrange().Key(key).Offset(offset).Value(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Setrange().Key(key).Offset(offset).Value(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_set_range", err == nil)
 
 	return result, err
@@ -233,7 +277,7 @@ func (r *Redis) SetRange(ctx context.Context, key string, offset int64, value st
 
 func (r *Redis) StrLen(ctx context.Context, key string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Strlen().Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Strlen().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_strlen", err == nil)
 
 	return result, err
@@ -241,7 +285,7 @@ func (r *Redis) StrLen(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) MGet(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Mget().Key(keys...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Mget().Key(keys...).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_mget", err == nil)
 
 	return result, err
@@ -253,7 +297,7 @@ func (r *Redis) MSet(ctx context.Context, kvs map[string]string) error {
 	for k, v := range kvs {
 		kvObj.KeyValue(k, v)
 	}
-	_, err := r.conn.Do(ctx, kvObj.Build()).ToString()
+	_, err := r.do(ctx, kvObj.Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_mset", err == nil)
 
 	return err
@@ -265,7 +309,7 @@ func (r *Redis) HGetCompleted(key, field string) rueidis.Completed {
 
 func (r *Redis) HGet(ctx context.Context, key, field string) (string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hget().Key(key).Field(field).Build()).ToString()
+	result, err := r.do(ctx, r.conn.B().Hget().Key(key).Field(field).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_hget", err == nil)
 
 	return result, err
@@ -273,7 +317,7 @@ func (r *Redis) HGet(ctx context.Context, key, field string) (string, error) {
 
 func (r *Redis) HSet(ctx context.Context, key, field, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hset().Key(key).FieldValue().FieldValue(field, value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Hset().Key(key).FieldValue().FieldValue(field, value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_hset", err == nil)
 
 	return result, err
@@ -281,7 +325,7 @@ func (r *Redis) HSet(ctx context.Context, key, field, value string) (int64, erro
 
 func (r *Redis) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hdel().Key(key).Field(fields...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Hdel().Key(key).Field(fields...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_hdel", err == nil)
 
 	return result, err
@@ -293,7 +337,7 @@ func (r *Redis) HDelCompleted(ctx context.Context, key string, fields ...string)
 
 func (r *Redis) HGetAll(ctx context.Context, key string) (map[string]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hgetall().Key(key).Build()).ToMap()
+	result, err := r.do(ctx, r.conn.B().Hgetall().Key(key).Build()).ToMap()
 	r.writeTimingAndCounter(startTime, "redis_hgetall", err == nil)
 
 	return result, err
@@ -301,7 +345,7 @@ func (r *Redis) HGetAll(ctx context.Context, key string) (map[string]rueidis.Red
 
 func (r *Redis) HIncrBy(ctx context.Context, key, field string, value int64) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hincrby().Key(key).Field(field).Increment(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Hincrby().Key(key).Field(field).Increment(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_hincrby", err == nil)
 
 	return result, err
@@ -309,7 +353,7 @@ func (r *Redis) HIncrBy(ctx context.Context, key, field string, value int64) (in
 
 func (r *Redis) HKeys(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hkeys().Key(key).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Hkeys().Key(key).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_hkeys", err == nil)
 
 	return result, err
@@ -317,7 +361,7 @@ func (r *Redis) HKeys(ctx context.Context, key string) ([]rueidis.RedisMessage,
 
 func (r *Redis) HLen(ctx context.Context, key string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hlen().Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Hlen().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_hlen", err == nil)
 
 	return result, err
@@ -325,25 +369,34 @@ func (r *Redis) HLen(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) HMGet(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hmget().Key(key).Field(fields...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Hmget().Key(key).Field(fields...).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_hmget", err == nil)
 
 	return result, err
 }
 
+// HMSet sets fields of key, the TTL rule of the key applies.
 func (r *Redis) HMSet(ctx context.Context, key string, kvs map[string]string) error {
 	startTime := time.Now()
-	kvObj := r.conn.B().Hmset().Key(key).FieldValue()
-	for k, v := range kvs {
-		kvObj.FieldValue(k, v)
+	var err error
+	if rule, ok := r.ttlRules.lookup(key); ok {
+		err = r.ttlHMSet(ctx, rule, key, kvs)
+	} else {
+		err = r.do(ctx, r.HMSetComplete(key, kvs)).Error()
 	}
-	err := r.conn.Do(ctx, kvObj.Build()).Error()
 	r.writeTimingAndCounter(startTime, "redis_hmset", err == nil)
 
 	return err
 }
 
+// HMSetComplete builds HMSET, the TTL rule of the key applies: the
+// builder returns EVALSHA of the rule script instead of HMSET. The
+// script is loaded by New, pipelines of Redis resend the command
+// as EVAL when the server lost it since.
 func (r *Redis) HMSetComplete(key string, kvs map[string]string) rueidis.Completed {
+	if rule, ok := r.ttlRules.lookup(key); ok {
+		return r.ttlHMSetCompleted(rule, key, kvs)
+	}
 	kvObj := r.conn.B().Hmset().Key(key).FieldValue()
 	for k, v := range kvs {
 		kvObj.FieldValue(k, v)
@@ -353,7 +406,7 @@ func (r *Redis) HMSetComplete(key string, kvs map[string]string) rueidis.Complet
 
 func (r *Redis) HSetNX(ctx context.Context, key, field, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hsetnx().Key(key).Field(field).Value(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Hsetnx().Key(key).Field(field).Value(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_hsetnx", err == nil)
 
 	return result, err
@@ -361,7 +414,7 @@ func (r *Redis) HSetNX(ctx context.Context, key, field, value string) (int64, er
 
 func (r *Redis) HVals(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hvals().Key(key).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Hvals().Key(key).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_hvals", err == nil)
 
 	return result, err
@@ -369,7 +422,7 @@ func (r *Redis) HVals(ctx context.Context, key string) ([]rueidis.RedisMessage,
 
 func (r *Redis) LIndex(ctx context.Context, key string, index int64) (string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lindex().Key(key).Index(index).Build()).ToString()
+	result, err := r.do(ctx, r.conn.B().Lindex().Key(key).Index(index).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_lindex", err == nil)
 
 	return result, err
@@ -380,9 +433,9 @@ func (r *Redis) LInsert(ctx context.Context, key, pivot, value string, before bo
 	var result int64
 	var err error
 	if before {
-		result, err = r.conn.Do(ctx, r.conn.B().Linsert().Key(key).Before().Pivot(pivot).Element(value).Build()).ToInt64()
+		result, err = r.do(ctx, r.conn.B().Linsert().Key(key).Before().Pivot(pivot).Element(value).Build()).ToInt64()
 	} else {
-		result, err = r.conn.Do(ctx, r.conn.B().Linsert().Key(key).After().Pivot(pivot).Element(value).Build()).ToInt64()
+		result, err = r.do(ctx, r.conn.B().Linsert().Key(key).After().Pivot(pivot).Element(value).Build()).ToInt64()
 	}
 	r.writeTimingAndCounter(startTime, "redis_linsert", err == nil)
 
@@ -391,7 +444,7 @@ func (r *Redis) LInsert(ctx context.Context, key, pivot, value string, before bo
 
 func (r *Redis) LLen(ctx context.Context, key string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Llen().Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Llen().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_llen", err == nil)
 
 	return result, err
@@ -399,7 +452,7 @@ func (r *Redis) LLen(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) LPop(ctx context.Context, key string) (string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lpop().Key(key).Build()).ToString()
+	result, err := r.do(ctx, r.conn.B().Lpop().Key(key).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_lpop", err == nil)
 
 	return result, err
@@ -407,7 +460,7 @@ func (r *Redis) LPop(ctx context.Context, key string) (string, error) {
 
 func (r *Redis) LPush(ctx context.Context, key string, values ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lpush().Key(key).Element(values...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Lpush().Key(key).Element(values...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_lpush", err == nil)
 
 	return result, err
@@ -415,7 +468,7 @@ func (r *Redis) LPush(ctx context.Context, key string, values ...string) (int64,
 
 func (r *Redis) LPushX(ctx context.Context, key, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lpushx().Key(key).Element(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Lpushx().Key(key).Element(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_lpushx", err == nil)
 
 	return result, err
@@ -423,7 +476,7 @@ func (r *Redis) LPushX(ctx context.Context, key, value string) (int64, error) {
 
 func (r *Redis) LRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lrange().Key(key).Start(start).Stop(stop).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Lrange().Key(key).Start(start).Stop(stop).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_lrange", err == nil)
 
 	return result, err
@@ -431,7 +484,7 @@ func (r *Redis) LRange(ctx context.Context, key string, start, stop int64) ([]ru
 
 func (r *Redis) LRem(ctx context.Context, key string, count int64, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Lrem().Key(key).Count(count).Element(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Lrem().Key(key).Count(count).Element(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_lrem", err == nil)
 
 	return result, err
@@ -439,7 +492,7 @@ func (r *Redis) LRem(ctx context.Context, key string, count int64, value string)
 
 func (r *Redis) LSet(ctx context.Context, key string, index int64, value string) error {
 	startTime := time.Now()
-	err := r.conn.Do(ctx, r.conn.B().Lset().Key(key).Index(index).Element(value).Build()).Error()
+	err := r.do(ctx, r.conn.B().Lset().Key(key).Index(index).Element(value).Build()).Error()
 	r.writeTimingAndCounter(startTime, "redis_lset", err == nil)
 
 	return err
@@ -447,7 +500,7 @@ func (r *Redis) LSet(ctx context.Context, key string, index int64, value string)
 
 func (r *Redis) LTrim(ctx context.Context, key string, start, stop int64) error {
 	startTime := time.Now()
-	err := r.conn.Do(ctx, r.conn.B().Ltrim().Key(key).Start(start).Stop(stop).Build()).Error()
+	err := r.do(ctx, r.conn.B().Ltrim().Key(key).Start(start).Stop(stop).Build()).Error()
 	r.writeTimingAndCounter(startTime, "redis_ltrim", err == nil)
 
 	return err
@@ -455,7 +508,7 @@ func (r *Redis) LTrim(ctx context.Context, key string, start, stop int64) error
 
 func (r *Redis) RPop(ctx context.Context, key string) (string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Rpop().Key(key).Build()).ToString()
+	result, err := r.do(ctx, r.conn.B().Rpop().Key(key).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_rpop", err == nil)
 
 	return result, err
@@ -463,7 +516,7 @@ func (r *Redis) RPop(ctx context.Context, key string) (string, error) {
 
 func (r *Redis) RPush(ctx context.Context, key string, values ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Rpush().Key(key).Element(values...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Rpush().Key(key).Element(values...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_rpush", err == nil)
 
 	return result, err
@@ -471,7 +524,7 @@ func (r *Redis) RPush(ctx context.Context, key string, values ...string) (int64,
 
 func (r *Redis) RPushX(ctx context.Context, key, value string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Rpushx().Key(key).Element(value).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Rpushx().Key(key).Element(value).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_rpushx", err == nil)
 
 	return result, err
@@ -479,7 +532,7 @@ func (r *Redis) RPushX(ctx context.Context, key, value string) (int64, error) {
 
 func (r *Redis) SAdd(ctx context.Context, key string, members ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sadd().Key(key).Member(members...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Sadd().Key(key).Member(members...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_sadd", err == nil)
 
 	return result, err
@@ -491,7 +544,7 @@ func (r *Redis) SAddCompleted(key string, members ...string) rueidis.Completed {
 
 func (r *Redis) SCard(ctx context.Context, key string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Scard().Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Scard().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_scard", err == nil)
 
 	return result, err
@@ -499,7 +552,7 @@ func (r *Redis) SCard(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) SDiff(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sdiff().Key(keys...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Sdiff().Key(keys...).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_sdiff", err == nil)
 
 	return result, err
@@ -507,7 +560,7 @@ func (r *Redis) SDiff(ctx context.Context, keys ...string) ([]rueidis.RedisMessa
 
 func (r *Redis) SInter(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sinter().Key(keys...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Sinter().Key(keys...).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_sinter", err == nil)
 
 	return result, err
@@ -515,7 +568,7 @@ func (r *Redis) SInter(ctx context.Context, keys ...string) ([]rueidis.RedisMess
 
 func (r *Redis) SIsMember(ctx context.Context, key, member string) (bool, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sismember().Key(key).Member(member).Build()).ToBool()
+	result, err := r.do(ctx, r.conn.B().Sismember().Key(key).Member(member).Build()).ToBool()
 	r.writeTimingAndCounter(startTime, "redis_sismember", err == nil)
 
 	return result, err
@@ -523,7 +576,7 @@ func (r *Redis) SIsMember(ctx context.Context, key, member string) (bool, error)
 
 func (r *Redis) SMembers(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Smembers().Key(key).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Smembers().Key(key).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_smembers", err == nil)
 
 	return result, err
@@ -536,7 +589,7 @@ func (r *Redis) SMembersCompleted(key string) rueidis.Completed {
 
 func (r *Redis) SMove(ctx context.Context, source, destination, member string) (bool, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Smove().Source(source).Destination(destination).Member(member).Build()).ToBool()
+	result, err := r.do(ctx, r.conn.B().Smove().Source(source).Destination(destination).Member(member).Build()).ToBool()
 	r.writeTimingAndCounter(startTime, "redis_smove", err == nil)
 
 	return result, err
@@ -544,7 +597,7 @@ func (r *Redis) SMove(ctx context.Context, source, destination, member string) (
 
 func (r *Redis) SPop(ctx context.Context, key string) (string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Spop().Key(key).Build()).ToString()
+	result, err := r.do(ctx, r.conn.B().Spop().Key(key).Build()).ToString()
 	r.writeTimingAndCounter(startTime, "redis_spop", err == nil)
 
 	return result, err
@@ -552,7 +605,7 @@ func (r *Redis) SPop(ctx context.Context, key string) (string, error) {
 
 func (r *Redis) SRandMember(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Srandmember().Key(key).Count(count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Srandmember().Key(key).Count(count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_srandmember", err == nil)
 
 	return result, err
@@ -560,7 +613,7 @@ func (r *Redis) SRandMember(ctx context.Context, key string, count int64) ([]rue
 
 func (r *Redis) SRem(ctx context.Context, key string, members ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Srem().Key(key).Member(members...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Srem().Key(key).Member(members...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_srem", err == nil)
 
 	return result, err
@@ -568,7 +621,7 @@ func (r *Redis) SRem(ctx context.Context, key string, members ...string) (int64,
 
 func (r *Redis) SUnion(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sunion().Key(keys...).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Sunion().Key(keys...).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_sunion", err == nil)
 
 	return result, err
@@ -576,7 +629,7 @@ func (r *Redis) SUnion(ctx context.Context, keys ...string) ([]rueidis.RedisMess
 
 func (r *Redis) ZAddXX(ctx context.Context, key string, score float64, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zadd().Key(key).Xx().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zadd().Key(key).Xx().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zadd_xx", err == nil)
 
 	return result, err
@@ -584,7 +637,7 @@ func (r *Redis) ZAddXX(ctx context.Context, key string, score float64, member st
 
 func (r *Redis) ZAddNX(ctx context.Context, key string, score float64, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zadd().Key(key).Nx().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zadd().Key(key).Nx().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zadd_nx", err == nil)
 
 	return result, err
@@ -592,7 +645,7 @@ func (r *Redis) ZAddNX(ctx context.Context, key string, score float64, member st
 
 func (r *Redis) ZAddCh(ctx context.Context, key string, score float64, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zadd().Key(key).Ch().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zadd().Key(key).Ch().ScoreMember().ScoreMember(score, member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zadd_ch", err == nil)
 
 	return result, err
@@ -600,7 +653,7 @@ func (r *Redis) ZAddCh(ctx context.Context, key string, score float64, member st
 
 func (r *Redis) ZAdd(ctx context.Context, key string, score float64, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zadd().Key(key).ScoreMember().ScoreMember(score, member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zadd().Key(key).ScoreMember().ScoreMember(score, member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zadd", err == nil)
 
 	return result, err
@@ -608,7 +661,7 @@ func (r *Redis) ZAdd(ctx context.Context, key string, score float64, member stri
 
 func (r *Redis) ZCard(ctx context.Context, key string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zcard().Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zcard().Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zcard", err == nil)
 
 	return result, err
@@ -616,7 +669,7 @@ func (r *Redis) ZCard(ctx context.Context, key string) (int64, error) {
 
 func (r *Redis) ZCount(ctx context.Context, key, min, max string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zcount().Key(key).Min(min).Max(max).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zcount().Key(key).Min(min).Max(max).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zcount", err == nil)
 
 	return result, err
@@ -624,7 +677,7 @@ func (r *Redis) ZCount(ctx context.Context, key, min, max string) (int64, error)
 
 func (r *Redis) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zincrby().Key(key).Increment(increment).Member(member).Build()).ToFloat64()
+	result, err := r.do(ctx, r.conn.B().Zincrby().Key(key).Increment(increment).Member(member).Build()).ToFloat64()
 	r.writeTimingAndCounter(startTime, "redis_zincrby", err == nil)
 
 	return result, err
@@ -632,7 +685,7 @@ func (r *Redis) ZIncrBy(ctx context.Context, key string, increment float64, memb
 
 func (r *Redis) ZInterStore(ctx context.Context, destination, key string, numkeys int64) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zinterstore().Destination(destination).Numkeys(numkeys).Key(key).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zinterstore().Destination(destination).Numkeys(numkeys).Key(key).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zinterstore", err == nil)
 
 	return result, err
@@ -640,7 +693,7 @@ func (r *Redis) ZInterStore(ctx context.Context, destination, key string, numkey
 
 func (r *Redis) ZLexCount(ctx context.Context, key, min, max string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zlexcount().Key(key).Min(min).Max(max).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zlexcount().Key(key).Min(min).Max(max).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zlexcount", err == nil)
 
 	return result, err
@@ -648,7 +701,7 @@ func (r *Redis) ZLexCount(ctx context.Context, key, min, max string) (int64, err
 
 func (r *Redis) ZPopMax(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zpopmax().Key(key).Count(count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zpopmax().Key(key).Count(count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zpopmax", err == nil)
 
 	return result, err
@@ -656,7 +709,7 @@ func (r *Redis) ZPopMax(ctx context.Context, key string, count int64) ([]rueidis
 
 func (r *Redis) ZPopMin(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zpopmin().Key(key).Count(count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zpopmin().Key(key).Count(count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zpopmin", err == nil)
 
 	return result, err
@@ -664,7 +717,7 @@ func (r *Redis) ZPopMin(ctx context.Context, key string, count int64) ([]rueidis
 
 func (r *Redis) ZRange(ctx context.Context, key, start, stop string) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrange().Key(key).Min(start).Max(stop).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrange().Key(key).Min(start).Max(stop).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrange", err == nil)
 
 	return result, err
@@ -672,7 +725,7 @@ func (r *Redis) ZRange(ctx context.Context, key, start, stop string) ([]rueidis.
 
 func (r *Redis) ZRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrangebylex().Key(key).Min(min).Max(max).Limit(offset, count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrangebylex().Key(key).Min(min).Max(max).Limit(offset, count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrange_by_lex", err == nil)
 
 	return result, err
@@ -680,7 +733,7 @@ func (r *Redis) ZRangeByLex(ctx context.Context, key, min, max string, offset, c
 
 func (r *Redis) ZRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrangebyscore().Key(key).Min(min).Max(max).Limit(offset, count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrangebyscore().Key(key).Min(min).Max(max).Limit(offset, count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrange_by_score", err == nil)
 
 	return result, err
@@ -688,7 +741,7 @@ func (r *Redis) ZRangeByScore(ctx context.Context, key, min, max string, offset,
 
 func (r *Redis) ZRank(ctx context.Context, key, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrank().Key(key).Member(member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zrank().Key(key).Member(member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrank", err == nil)
 
 	return result, err
@@ -696,7 +749,7 @@ func (r *Redis) ZRank(ctx context.Context, key, member string) (int64, error) {
 
 func (r *Redis) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrem().Key(key).Member(members...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zrem().Key(key).Member(members...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrem", err == nil)
 
 	return result, err
@@ -704,7 +757,7 @@ func (r *Redis) ZRem(ctx context.Context, key string, members ...string) (int64,
 
 func (r *Redis) ZRemRangeByLex(ctx context.Context, key, min, max string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zremrangebylex().Key(key).Min(min).Max(max).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zremrangebylex().Key(key).Min(min).Max(max).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrem_range_by_lex", err == nil)
 
 	return result, err
@@ -712,7 +765,7 @@ func (r *Redis) ZRemRangeByLex(ctx context.Context, key, min, max string) (int64
 
 func (r *Redis) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zremrangebyrank().Key(key).Start(start).Stop(stop).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zremrangebyrank().Key(key).Start(start).Stop(stop).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrem_range_by_rank", err == nil)
 
 	return result, err
@@ -720,7 +773,7 @@ func (r *Redis) ZRemRangeByRank(ctx context.Context, key string, start, stop int
 
 func (r *Redis) ZRemRangeByScore(ctx context.Context, key, min, max string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zremrangebyscore().Key(key).Min(min).Max(max).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zremrangebyscore().Key(key).Min(min).Max(max).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrem_range_by_score", err == nil)
 
 	return result, err
@@ -728,7 +781,7 @@ func (r *Redis) ZRemRangeByScore(ctx context.Context, key, min, max string) (int
 
 func (r *Redis) ZRevRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrevrange().Key(key).Start(start).Stop(stop).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrevrange().Key(key).Start(start).Stop(stop).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrevrange", err == nil)
 
 	return result, err
@@ -736,7 +789,7 @@ func (r *Redis) ZRevRange(ctx context.Context, key string, start, stop int64) ([
 
 func (r *Redis) ZRevRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrevrangebylex().Key(key).Max(max).Min(min).Limit(offset, count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrevrangebylex().Key(key).Max(max).Min(min).Limit(offset, count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrevrange_by_lex", err == nil)
 
 	return result, err
@@ -744,7 +797,7 @@ func (r *Redis) ZRevRangeByLex(ctx context.Context, key, min, max string, offset
 
 func (r *Redis) ZRevRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrevrangebyscore().Key(key).Max(max).Min(min).Limit(offset, count).Build()).ToArray()
+	result, err := r.do(ctx, r.conn.B().Zrevrangebyscore().Key(key).Max(max).Min(min).Limit(offset, count).Build()).ToArray()
 	r.writeTimingAndCounter(startTime, "redis_zrevrange_by_score", err == nil)
 
 	return result, err
@@ -752,7 +805,7 @@ func (r *Redis) ZRevRangeByScore(ctx context.Context, key, min, max string, offs
 
 func (r *Redis) ZRevRank(ctx context.Context, key, member string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zrevrank().Key(key).Member(member).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zrevrank().Key(key).Member(member).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zrevrank", err == nil)
 
 	return result, err
@@ -760,7 +813,7 @@ func (r *Redis) ZRevRank(ctx context.Context, key, member string) (int64, error)
 
 func (r *Redis) ZScore(ctx context.Context, key, member string) (float64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zscore().Key(key).Member(member).Build()).ToFloat64()
+	result, err := r.do(ctx, r.conn.B().Zscore().Key(key).Member(member).Build()).ToFloat64()
 	r.writeTimingAndCounter(startTime, "redis_zscore", err == nil)
 
 	return result, err
@@ -768,7 +821,7 @@ func (r *Redis) ZScore(ctx context.Context, key, member string) (float64, error)
 
 func (r *Redis) ZUnionStore(ctx context.Context, destination string, keys ...string) (int64, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zunionstore().Destination(destination).Numkeys(int64(len(keys))).Key(keys...).Build()).ToInt64()
+	result, err := r.do(ctx, r.conn.B().Zunionstore().Destination(destination).Numkeys(int64(len(keys))).Key(keys...).Build()).ToInt64()
 	r.writeTimingAndCounter(startTime, "redis_zunionstore", err == nil)
 
 	return result, err
@@ -780,15 +833,15 @@ func (r *Redis) Close() {
 
 func (r *Redis) Keys(ctx context.Context, pattern string) ([]string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Keys().Pattern(pattern).Build()).AsStrSlice()
-	r.metrics.WriteTimingAndCounter(startTime, "redis_keys", err == nil)
+	result, err := r.do(ctx, r.conn.B().Keys().Pattern(pattern).Build()).AsStrSlice()
+	r.writeTimingAndCounter(startTime, "redis_keys", err == nil)
 
 	return result, err
 }
 
 func (r *Redis) Scan(ctx context.Context, cursor uint64, match string, count int64) (uint64, []string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Scan().Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
+	result, err := r.do(ctx, r.conn.B().Scan().Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
 	r.writeTimingAndCounter(startTime, "redis_scan", err == nil)
 
 	return result.Cursor, result.Elements, err
@@ -806,7 +859,7 @@ func (r *Redis) ScanAllKeys(ctx context.Context, match string, count int64) (map
 			defer wg.Done()
 			var cursor uint64 = 0
 			for {
-				result, err := node.Do(ctx, r.conn.B().Scan().Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
+				result, err := r.doOn(ctx, node, r.conn.B().Scan().Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
 				if err != nil {
 					errAll = errors.Join(errAll, err)
 					break
@@ -834,7 +887,7 @@ func (r *Redis) ScanAllFields(ctx context.Context, key string, fieldMatch string
 	var errAll error
 	var cursor uint64 = 0
 	for {
-		result, err := r.conn.Do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(fieldMatch).Count(count).Build()).AsScanEntry()
+		result, err := r.do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(fieldMatch).Count(count).Build()).AsScanEntry()
 		if err != nil {
 			errAll = errors.Join(errAll, err)
 			break
@@ -851,7 +904,7 @@ func (r *Redis) ScanAllFields(ctx context.Context, key string, fieldMatch string
 
 func (r *Redis) SScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Sscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
+	result, err := r.do(ctx, r.conn.B().Sscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
 	r.writeTimingAndCounter(startTime, "redis_sscan", err == nil)
 
 	return result.Cursor, result.Elements, err
@@ -859,7 +912,7 @@ func (r *Redis) SScan(ctx context.Context, key string, cursor uint64, match stri
 
 func (r *Redis) HScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
+	result, err := r.do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
 	r.writeTimingAndCounter(startTime, "redis_hscan", err == nil)
 
 	return result.Cursor, result.Elements, err
@@ -867,7 +920,7 @@ func (r *Redis) HScan(ctx context.Context, key string, cursor uint64, match stri
 
 func (r *Redis) ZScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
 	startTime := time.Now()
-	result, err := r.conn.Do(ctx, r.conn.B().Zscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
+	result, err := r.do(ctx, r.conn.B().Zscan().Key(key).Cursor(cursor).Match(match).Count(count).Build()).AsScanEntry()
 	r.writeTimingAndCounter(startTime, "redis_zscan", err == nil)
 
 	return result.Cursor, result.Elements, err
@@ -887,14 +940,41 @@ func (r *Redis) CTGet(key string) rueidis.CacheableTTL {
 
 func (r *Redis) DoMultiCache(ctx context.Context, commands ...rueidis.CacheableTTL) []rueidis.RedisResult {
 	startTime := time.Now()
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	for _, cmd := range commands {
+		r.observeKey(cmd.Cmd.Commands())
+	}
+	recorded := r.recorder.captureMulti(len(commands), func(idx int) rueidis.Completed { return rueidis.Completed(commands[idx].Cmd) })
+	errs := r.injectMulti(ctx, func(idx int) string { return commands[idx].Cmd.Commands()[0] }, len(commands))
+	commands, failed := splitFaults(commands, errs)
+	r.addInFlight(len(commands))
 	result := r.conn.DoMultiCache(ctx, commands...)
+	r.addInFlight(-len(commands))
+	r.observePipeline(len(commands))
+	r.observeCache(result...)
+	result = mergeFaults(errs, result, r.fail(cacheFailed(failed)))
+	r.recorder.recordMulti(recorded, startTime, result)
 	r.writeTimingAndCounter(startTime, "redis_domulticache", true)
 	return result
 }
 
 func (r *Redis) DoCache(ctx context.Context, cmd rueidis.CacheableTTL) rueidis.RedisResult {
 	startTime := time.Now()
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	r.observeKey(cmd.Cmd.Commands())
+	recorded := r.recorder.capture(rueidis.Completed(cmd.Cmd))
+	if err := r.inject(ctx, cmd.Cmd.Commands()[0]); err != nil {
+		r.recorder.record(recorded, startTime, reply{err: err})
+		r.writeTimingAndCounter(startTime, "redis_docache", false)
+		return r.fail(cacheFailed([]rueidis.CacheableTTL{cmd}))[0]
+	}
+	r.addInFlight(1)
 	result := r.conn.DoCache(ctx, cmd.Cmd, cmd.TTL)
+	r.addInFlight(-1)
+	r.recorder.record(recorded, startTime, reply{result: result})
+	r.observeCache(result)
 	r.writeTimingAndCounter(startTime, "redis_docache", true)
 	return result
 }
@@ -909,12 +989,27 @@ func (r *Redis) HMGetCompleted(ctx context.Context, key string, fields ...string
 }
 
 func (r *Redis) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
-	resp := r.conn.DoMulti(ctx, multi...)
+	startTime := time.Now()
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	for _, cmd := range multi {
+		r.observeKey(cmd.Commands())
+	}
+	recorded := r.recorder.captureMulti(len(multi), func(idx int) rueidis.Completed { return multi[idx] })
+	errs := r.injectMulti(ctx, func(idx int) string { return multi[idx].Commands()[0] }, len(multi))
+	multi, failed := splitFaults(multi, errs)
+	scripts := captureEvalshas(multi)
+	r.addInFlight(len(multi))
+	resp := scripts.resend(ctx, r.conn, r.conn.DoMulti(ctx, multi...))
+	r.addInFlight(-len(multi))
+	r.observePipeline(len(multi))
+	resp = mergeFaults(errs, resp, r.fail(failed))
+	r.recorder.recordMulti(recorded, startTime, resp)
 	return resp
 }
 
 func (r *Redis) ScanEntryFields(ctx context.Context, key string, fieldMatch string, cursor uint64, count int64) (*rueidis.ScanEntry, error) {
-	result, err := r.conn.Do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(fieldMatch).Count(count).Build()).AsScanEntry()
+	result, err := r.do(ctx, r.conn.B().Hscan().Key(key).Cursor(cursor).Match(fieldMatch).Count(count).Build()).AsScanEntry()
 	if err != nil {
 		return &rueidis.ScanEntry{}, err
 	}
@@ -925,8 +1020,13 @@ func (r *Redis) HGetAllCompleted(ctx context.Context, key string) rueidis.Comple
 	return r.conn.B().Hgetall().Key(key).Build()
 }
 
+// DoMultiExec sends multi in one pipeline and returns the first
+// error. The durability of ctx or of the rules applies.
 func (r *Redis) DoMultiExec(ctx context.Context, multi rueidis.Commands) error {
-	result := r.DoMulti(ctx, multi...)
+	result, err := r.pipeline(ctx, multi)
+	if err != nil {
+		return err
+	}
 	if err := HasError(result); err != nil {
 		return err
 	}
@@ -5,16 +5,31 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/rueidis"
 )
 
+// Multi mirrors every command to several redis connections.
+// Writes are applied to each connection in order and stop at the
+// first error, the result of the main connection is returned,
+// except for Del, DelMulti, HSet and HDel that return the sum over
+// all connections. Reads are served by the first connection that
+// answers without an error. Pipelines with writes are sent to every
+// connection, read-only pipelines, client-side cached reads and
+// builders use the main connection.
 type Multi struct {
 	mainConn *Redis
 	conn     []*Redis
+	shadow   atomic.Pointer[shadow]
 }
 
+var (
+	_ Client = (*Redis)(nil)
+	_ Client = (*Multi)(nil)
+)
+
 func NewMultiple(cfg []Config, metrics metrics) (*Multi, error) {
 
 	if len(cfg) == 0 {
@@ -28,7 +43,6 @@ func NewMultiple(cfg []Config, metrics metrics) (*Multi, error) {
 		if err != nil {
 			return nil, err
 		}
-		r.connectionName = config.Name
 		rm.conn = append(rm.conn, r)
 	}
 	rm.mainConn = rm.conn[0]
@@ -49,39 +63,74 @@ func HasError(ds []rueidis.RedisResult) error {
 	return nil
 }
 
-func (r *Multi) DoMulti(ctx context.Context, multi rueidis.Commands) (result []rueidis.RedisResult) {
-	return r.mainConn.DoMulti(ctx, multi...)
+type scanResult struct {
+	cursor   uint64
+	elements []string
 }
 
-func (r *Multi) HDelCompleted(ctx context.Context, key string, fields ...string) rueidis.Completed {
-	return r.mainConn.conn.B().Hdel().Key(key).Field(fields...).Build()
+// readAll returns the result of the first connection that
+// answers without an error. The read of the main connection is
+// mirrored to the shadow connection when shadow reads are on.
+func readAll[T any](ctx context.Context, r *Multi, command string, call func(ctx context.Context, conn *Redis) (T, error)) (result T, err error) {
+	for idx, conn := range r.conn {
+		result, err = call(ctx, conn)
+		if idx == 0 {
+			shadowRead(ctx, r, command, result, err, call)
+		}
+		if err == nil {
+			return result, nil
+		}
+	}
+	return result, err
+}
+
+// writeAll applies call to every connection and returns the
+// result of the main connection. A nil reply doesn't stop the
+// fan-out, it is returned when the main connection replied so.
+func writeAll[T any](r *Multi, call func(conn *Redis) (T, error)) (result T, err error) {
+	for idx, conn := range r.conn {
+		value, callErr := call(conn)
+		if callErr != nil && !rueidis.IsRedisNil(callErr) {
+			var empty T
+			return empty, callErr
+		}
+		if idx == 0 {
+			result, err = value, callErr
+		}
+	}
+	return result, err
 }
 
-func (r *Multi) HDel(ctx context.Context, key string, fields ...string) (result int64, err error) {
+// sumAll applies call to every connection and returns the sum of
+// the replies.
+func sumAll(r *Multi, call func(conn *Redis) (int64, error)) (result int64, err error) {
 	for _, conn := range r.conn {
 		var cnt int64
-		if cnt, err = conn.HDel(ctx, key, fields...); err != nil {
+		if cnt, err = call(conn); err != nil {
 			return 0, err
 		}
 		result += cnt
 	}
-
 	return result, nil
 }
 
+func (r *Multi) writeErr(call func(conn *Redis) error) error {
+	_, err := writeAll(r, func(conn *Redis) (struct{}, error) {
+		return struct{}{}, call(conn)
+	})
+	return err
+}
+
 func (r *Multi) DoMultiExec(ctx context.Context, multi rueidis.Commands) error {
 	for idx := len(r.conn) - 1; idx >= 0; idx-- {
 		conn := r.conn[idx]
-		var result []rueidis.RedisResult
-		if idx == 0 {
-			result = conn.DoMulti(ctx, multi...)
-		} else {
-			cmd := make([]rueidis.Completed, 0, len(multi))
-			for _, commands := range multi {
-				tmp := commands.Commands()
-				cmd = append(cmd, conn.conn.B().Arbitrary(tmp[:2]...).Args(tmp[2:]...).Build())
-			}
-			result = conn.DoMulti(ctx, cmd...)
+		cmds := multi
+		if idx > 0 {
+			cmds = rebuild(conn, multi)
+		}
+		result, err := conn.pipeline(ctx, cmds)
+		if err != nil {
+			return err
 		}
 		if err := HasError(result); err != nil {
 			return err
@@ -90,191 +139,677 @@ func (r *Multi) DoMultiExec(ctx context.Context, multi rueidis.Commands) error {
 	return nil
 }
 
-func (r *Multi) HGetAll(ctx context.Context, key string) (result map[string]rueidis.RedisMessage, err error) {
-	for _, conn := range r.conn {
-		if result, err = conn.HGetAll(ctx, key); err == nil {
-			return result, nil
+// rebuild copies commands for conn, since commands are recycled
+// once they are sent.
+func rebuild(conn *Redis, multi rueidis.Commands) rueidis.Commands {
+	cmd := make(rueidis.Commands, 0, len(multi))
+	for idx := range multi {
+		cmd = append(cmd, copyCompleted(conn, &multi[idx]))
+	}
+	return cmd
+}
+
+// copyCompleted builds a copy of c for conn with the same flags
+// and key slot. A built command doesn't keep its keys, the slot is
+// taken from the first token that hashes to the slot of c.
+func copyCompleted(conn *Redis, c *rueidis.Completed) rueidis.Completed {
+	tmp := c.Commands()
+	arbitrary := conn.conn.B().Arbitrary(tmp...)
+
+	var cmd rueidis.Completed
+	switch {
+	case c.IsReadOnly():
+		cmd = arbitrary.ReadOnly()
+	case c.IsBlock():
+		cmd = arbitrary.Blocking()
+	default:
+		cmd = arbitrary.Build()
+	}
+
+	for _, token := range tmp[1:] {
+		if keyed := cmd.SetSlot(token); keyed.Slot() == c.Slot() {
+			return keyed
 		}
 	}
-	return nil, err
+	return cmd
 }
 
-func (r *Multi) HGetCompleted(key, field string) rueidis.Completed {
-	return r.mainConn.conn.B().Hget().Key(key).Field(field).Build()
+// DelComplete is kept for compatibility.
+//
+// Deprecated: use DelCompleted.
+func (r *Multi) DelComplete(key string) rueidis.Completed {
+	return r.mainConn.DelCompleted(key)
 }
 
-func (r *Multi) HMSet(ctx context.Context, key string, kvs map[string]string) (err error) {
+func (r *Multi) Close() {
+	r.DisableShadow()
 	for _, conn := range r.conn {
-		if err = conn.HMSet(ctx, key, kvs); err != nil {
-			return err
-		}
+		conn.Close()
 	}
-	return nil
 }
 
-func (r *Multi) HMSetComplete(key string, kvs map[string]string) (result rueidis.Completed) {
-	kvObj := r.mainConn.conn.B().Hmset().Key(key).FieldValue()
-	for k, v := range kvs {
-		kvObj.FieldValue(k, v)
-	}
-	result = kvObj.Build()
-	return result
+func (r *Multi) Exists(ctx context.Context, key ...string) (bool, error) {
+	return readAll(ctx, r, "Exists", func(ctx context.Context, conn *Redis) (bool, error) {
+		return conn.Exists(ctx, key...)
+	})
+}
+
+func (r *Multi) Get(ctx context.Context, key string) (string, error) {
+	return readAll(ctx, r, "Get", func(ctx context.Context, conn *Redis) (string, error) {
+		return conn.Get(ctx, key)
+	})
+}
+
+func (r *Multi) GetMulti(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "GetMulti", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.GetMulti(ctx, keys...)
+	})
+}
+
+func (r *Multi) SetCompleted(ctx context.Context, key, value string, ttl time.Duration) rueidis.Completed {
+	return r.mainConn.SetCompleted(ctx, key, value, ttl)
+}
+
+func (r *Multi) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.Set(ctx, key, value, ttl)
+	})
+}
+
+func (r *Multi) Del(ctx context.Context, key string) (int64, error) {
+	return sumAll(r, func(conn *Redis) (int64, error) {
+		return conn.Del(ctx, key)
+	})
+}
+
+func (r *Multi) DelCompleted(key string) rueidis.Completed {
+	return r.mainConn.DelCompleted(key)
+}
+
+func (r *Multi) DelMulti(ctx context.Context, keys ...string) (int64, error) {
+	return sumAll(r, func(conn *Redis) (int64, error) {
+		return conn.DelMulti(ctx, keys...)
+	})
+}
+
+func (r *Multi) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.Expire(ctx, key, ttl)
+	})
+}
+
+func (r *Multi) ExpireAt(ctx context.Context, key string, at time.Time) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.ExpireAt(ctx, key, at)
+	})
 }
 
 func (r *Multi) ExpireAtCompleted(key string, at time.Time) rueidis.Completed {
-	return r.mainConn.conn.B().Expireat().Key(key).Timestamp(at.Unix()).Build()
+	return r.mainConn.ExpireAtCompleted(key, at)
 }
 
-func (r *Multi) HMGet(ctx context.Context, key string, fields ...string) (result []rueidis.RedisMessage, err error) {
-	for _, conn := range r.conn {
-		if result, err = conn.HMGet(ctx, key, fields...); err == nil {
-			return result, err
-		}
-	}
-	return nil, err
+func (r *Multi) TTL(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "TTL", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.TTL(ctx, key)
+	})
 }
 
-func (r *Multi) DelMulti(ctx context.Context, keys ...string) (result int64, err error) {
-	for _, conn := range r.conn {
-		var cnt int64
-		if cnt, err = conn.DelMulti(ctx, keys...); err != nil {
-			return 0, err
-		}
-		result += cnt
-	}
-	return result, nil
+func (r *Multi) PTTL(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "PTTL", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.PTTL(ctx, key)
+	})
 }
 
-func (r *Multi) SAdd(ctx context.Context, key string, members ...string) (result int64, err error) {
-	for _, conn := range r.conn {
-		if result, err = conn.SAdd(ctx, key, members...); err != nil {
-			return 0, err
-		}
-	}
-	return result, nil
+func (r *Multi) Incr(ctx context.Context, key string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.Incr(ctx, key)
+	})
+}
+
+func (r *Multi) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.IncrBy(ctx, key, value)
+	})
+}
+
+func (r *Multi) GetRange(ctx context.Context, key string, start, end int64) (string, error) {
+	return readAll(ctx, r, "GetRange", func(ctx context.Context, conn *Redis) (string, error) {
+		return conn.GetRange(ctx, key, start, end)
+	})
+}
+
+func (r *Multi) SetRange(ctx context.Context, key string, offset int64, value string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.SetRange(ctx, key, offset, value)
+	})
+}
+
+func (r *Multi) StrLen(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "StrLen", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.StrLen(ctx, key)
+	})
+}
+
+func (r *Multi) MGet(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "MGet", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.MGet(ctx, keys...)
+	})
+}
+
+func (r *Multi) MSet(ctx context.Context, kvs map[string]string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.MSet(ctx, kvs)
+	})
+}
+
+func (r *Multi) HGetCompleted(key, field string) rueidis.Completed {
+	return r.mainConn.HGetCompleted(key, field)
+}
+
+func (r *Multi) HGet(ctx context.Context, key, field string) (string, error) {
+	return readAll(ctx, r, "HGet", func(ctx context.Context, conn *Redis) (string, error) {
+		return conn.HGet(ctx, key, field)
+	})
+}
+
+func (r *Multi) HSet(ctx context.Context, key, field, value string) (int64, error) {
+	return sumAll(r, func(conn *Redis) (int64, error) {
+		return conn.HSet(ctx, key, field, value)
+	})
+}
+
+func (r *Multi) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return sumAll(r, func(conn *Redis) (int64, error) {
+		return conn.HDel(ctx, key, fields...)
+	})
+}
+
+func (r *Multi) HDelCompleted(ctx context.Context, key string, fields ...string) rueidis.Completed {
+	return r.mainConn.HDelCompleted(ctx, key, fields...)
+}
+
+func (r *Multi) HGetAll(ctx context.Context, key string) (map[string]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "HGetAll", func(ctx context.Context, conn *Redis) (map[string]rueidis.RedisMessage, error) {
+		return conn.HGetAll(ctx, key)
+	})
+}
+
+func (r *Multi) HIncrBy(ctx context.Context, key, field string, value int64) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.HIncrBy(ctx, key, field, value)
+	})
+}
+
+func (r *Multi) HKeys(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "HKeys", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.HKeys(ctx, key)
+	})
+}
+
+func (r *Multi) HLen(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "HLen", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.HLen(ctx, key)
+	})
+}
+
+func (r *Multi) HMGet(ctx context.Context, key string, fields ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "HMGet", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.HMGet(ctx, key, fields...)
+	})
+}
+
+func (r *Multi) HMSet(ctx context.Context, key string, kvs map[string]string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.HMSet(ctx, key, kvs)
+	})
+}
+
+func (r *Multi) HMSetComplete(key string, kvs map[string]string) rueidis.Completed {
+	return r.mainConn.HMSetComplete(key, kvs)
+}
+
+func (r *Multi) HSetNX(ctx context.Context, key, field, value string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.HSetNX(ctx, key, field, value)
+	})
+}
+
+func (r *Multi) HVals(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "HVals", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.HVals(ctx, key)
+	})
+}
+
+func (r *Multi) LIndex(ctx context.Context, key string, index int64) (string, error) {
+	return readAll(ctx, r, "LIndex", func(ctx context.Context, conn *Redis) (string, error) {
+		return conn.LIndex(ctx, key, index)
+	})
+}
+
+func (r *Multi) LInsert(ctx context.Context, key, pivot, value string, before bool) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.LInsert(ctx, key, pivot, value, before)
+	})
+}
+
+func (r *Multi) LLen(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "LLen", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.LLen(ctx, key)
+	})
+}
+
+func (r *Multi) LPop(ctx context.Context, key string) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.LPop(ctx, key)
+	})
+}
+
+func (r *Multi) LPush(ctx context.Context, key string, values ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.LPush(ctx, key, values...)
+	})
+}
+
+func (r *Multi) LPushX(ctx context.Context, key, value string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.LPushX(ctx, key, value)
+	})
+}
+
+func (r *Multi) LRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "LRange", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.LRange(ctx, key, start, stop)
+	})
+}
+
+func (r *Multi) LRem(ctx context.Context, key string, count int64, value string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.LRem(ctx, key, count, value)
+	})
+}
+
+func (r *Multi) LSet(ctx context.Context, key string, index int64, value string) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.LSet(ctx, key, index, value)
+	})
+}
+
+func (r *Multi) LTrim(ctx context.Context, key string, start, stop int64) error {
+	return r.writeErr(func(conn *Redis) error {
+		return conn.LTrim(ctx, key, start, stop)
+	})
+}
+
+func (r *Multi) RPop(ctx context.Context, key string) (string, error) {
+	return writeAll(r, func(conn *Redis) (string, error) {
+		return conn.RPop(ctx, key)
+	})
+}
+
+func (r *Multi) RPush(ctx context.Context, key string, values ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.RPush(ctx, key, values...)
+	})
+}
+
+func (r *Multi) RPushX(ctx context.Context, key, value string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.RPushX(ctx, key, value)
+	})
+}
+
+func (r *Multi) SAdd(ctx context.Context, key string, members ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.SAdd(ctx, key, members...)
+	})
 }
 
 func (r *Multi) SAddCompleted(key string, members ...string) rueidis.Completed {
-	return r.mainConn.conn.B().Sadd().Key(key).Member(members...).Build()
+	return r.mainConn.SAddCompleted(key, members...)
 }
 
-func (r *Multi) SRem(ctx context.Context, key string, members ...string) (result int64, err error) {
-	for _, conn := range r.conn {
-		if result, err = conn.SRem(ctx, key, members...); err != nil {
-			return 0, err
-		}
-	}
-	return result, nil
+func (r *Multi) SCard(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "SCard", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.SCard(ctx, key)
+	})
 }
 
-func (r *Multi) SMembers(ctx context.Context, key string) (result []rueidis.RedisMessage, err error) {
-	for _, conn := range r.conn {
-		if result, err = conn.SMembers(ctx, key); err == nil {
-			return result, err
-		}
-	}
-	return nil, err
+func (r *Multi) SDiff(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "SDiff", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.SDiff(ctx, keys...)
+	})
 }
 
-func (r *Multi) Scan(ctx context.Context, cursor uint64, match string, count int64) (uint64, []string, error) {
-	cursor, elements, err := r.mainConn.Scan(ctx, cursor, match, count)
-	if err != nil {
-		for i := 1; i < len(r.conn); i++ {
-			cursor, elements, err = r.conn[i].Scan(ctx, cursor, match, count)
-			if err == nil {
-				break
-			}
-		}
+func (r *Multi) SInter(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "SInter", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.SInter(ctx, keys...)
+	})
+}
 
-	}
-	return cursor, elements, err
+func (r *Multi) SIsMember(ctx context.Context, key, member string) (bool, error) {
+	return readAll(ctx, r, "SIsMember", func(ctx context.Context, conn *Redis) (bool, error) {
+		return conn.SIsMember(ctx, key, member)
+	})
 }
 
-func (r *Multi) ScanAllKeys(ctx context.Context, match string, count int64) (result map[string]struct{}, err error) {
-	result, err = r.mainConn.ScanAllKeys(ctx, match, count)
-	if err != nil {
-		for i := 1; i < len(r.conn); i++ {
-			result, err = r.conn[i].ScanAllKeys(ctx, match, count)
-			if err == nil {
-				break
-			}
-		}
-	}
-	return result, err
+func (r *Multi) SMembers(ctx context.Context, key string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "SMembers", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.SMembers(ctx, key)
+	})
+}
+
+func (r *Multi) SMembersCompleted(key string) rueidis.Completed {
+	return r.mainConn.SMembersCompleted(key)
+}
+
+func (r *Multi) SMove(ctx context.Context, source, destination, member string) (bool, error) {
+	return writeAll(r, func(conn *Redis) (bool, error) {
+		return conn.SMove(ctx, source, destination, member)
+	})
 }
 
-func (r *Multi) ScanAllFields(ctx context.Context, key string, fieldMatch string, count int64) (result []string, err error) {
-	result, err = r.mainConn.ScanAllFields(ctx, key, fieldMatch, count)
+// SPop pops a random member on the main connection and removes
+// the same member on the other connections. The popped member is
+// returned even if the removal fails.
+func (r *Multi) SPop(ctx context.Context, key string) (string, error) {
+	member, err := r.mainConn.SPop(ctx, key)
 	if err != nil {
-		for i := 1; i < len(r.conn); i++ {
-			result, err = r.conn[i].ScanAllFields(ctx, key, fieldMatch, count)
-			if err == nil {
-				break
-			}
+		return member, err
+	}
+	for _, conn := range r.conn[1:] {
+		if _, err := conn.SRem(ctx, key, member); err != nil {
+			return member, err
 		}
 	}
-	return result, err
+	return member, nil
 }
 
-func (r *Multi) HSet(ctx context.Context, key, field, value string) (result int64, err error) {
-	for _, conn := range r.conn {
-		var cnt int64
-		if cnt, err = conn.HSet(ctx, key, field, value); err != nil {
-			return 0, err
-		}
-		result += cnt
-	}
+func (r *Multi) SRandMember(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "SRandMember", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.SRandMember(ctx, key, count)
+	})
+}
 
-	return result, nil
+func (r *Multi) SRem(ctx context.Context, key string, members ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.SRem(ctx, key, members...)
+	})
 }
 
-func (r *Multi) Del(ctx context.Context, key string) (result int64, err error) {
-	for _, conn := range r.conn {
-		var cnt int64
-		if cnt, err = conn.Del(ctx, key); err != nil {
-			return 0, err
-		}
-		result += cnt
-	}
+func (r *Multi) SUnion(ctx context.Context, keys ...string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "SUnion", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.SUnion(ctx, keys...)
+	})
+}
 
-	return result, nil
+func (r *Multi) ZAddXX(ctx context.Context, key string, score float64, member string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZAddXX(ctx, key, score, member)
+	})
 }
 
-func (r *Multi) DelComplete(key string) rueidis.Completed {
-	return r.mainConn.DelCompleted(key)
+func (r *Multi) ZAddNX(ctx context.Context, key string, score float64, member string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZAddNX(ctx, key, score, member)
+	})
 }
 
-func (r *Multi) Set(ctx context.Context, key, value string, ttl time.Duration) error {
-	for _, conn := range r.conn {
-		if err := conn.Set(ctx, key, value, ttl); err != nil {
-			return err
-		}
-	}
+func (r *Multi) ZAddCh(ctx context.Context, key string, score float64, member string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZAddCh(ctx, key, score, member)
+	})
+}
 
-	return nil
+func (r *Multi) ZAdd(ctx context.Context, key string, score float64, member string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZAdd(ctx, key, score, member)
+	})
 }
 
-func (r *Multi) SetCompleted(ctx context.Context, key, value string, ttl time.Duration) rueidis.Completed {
-	return r.mainConn.SetCompleted(ctx, key, value, ttl)
+func (r *Multi) ZCard(ctx context.Context, key string) (int64, error) {
+	return readAll(ctx, r, "ZCard", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.ZCard(ctx, key)
+	})
 }
 
-func (r *Multi) Get(ctx context.Context, key string) (result string, err error) {
-	for _, conn := range r.conn {
-		result, err = conn.Get(ctx, key)
-		if err == nil {
-			return result, nil
+func (r *Multi) ZCount(ctx context.Context, key, min, max string) (int64, error) {
+	return readAll(ctx, r, "ZCount", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.ZCount(ctx, key, min, max)
+	})
+}
+
+func (r *Multi) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
+	return writeAll(r, func(conn *Redis) (float64, error) {
+		return conn.ZIncrBy(ctx, key, increment, member)
+	})
+}
+
+func (r *Multi) ZInterStore(ctx context.Context, destination, key string, numkeys int64) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZInterStore(ctx, destination, key, numkeys)
+	})
+}
+
+func (r *Multi) ZLexCount(ctx context.Context, key, min, max string) (int64, error) {
+	return readAll(ctx, r, "ZLexCount", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.ZLexCount(ctx, key, min, max)
+	})
+}
+
+func (r *Multi) ZPopMax(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
+	return writeAll(r, func(conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZPopMax(ctx, key, count)
+	})
+}
+
+func (r *Multi) ZPopMin(ctx context.Context, key string, count int64) ([]rueidis.RedisMessage, error) {
+	return writeAll(r, func(conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZPopMin(ctx, key, count)
+	})
+}
+
+func (r *Multi) ZRange(ctx context.Context, key, start, stop string) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRange", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRange(ctx, key, start, stop)
+	})
+}
+
+func (r *Multi) ZRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRangeByLex", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRangeByLex(ctx, key, min, max, offset, count)
+	})
+}
+
+func (r *Multi) ZRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRangeByScore", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRangeByScore(ctx, key, min, max, offset, count)
+	})
+}
+
+func (r *Multi) ZRank(ctx context.Context, key, member string) (int64, error) {
+	return readAll(ctx, r, "ZRank", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.ZRank(ctx, key, member)
+	})
+}
+
+func (r *Multi) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZRem(ctx, key, members...)
+	})
+}
+
+func (r *Multi) ZRemRangeByLex(ctx context.Context, key, min, max string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZRemRangeByLex(ctx, key, min, max)
+	})
+}
+
+func (r *Multi) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZRemRangeByRank(ctx, key, start, stop)
+	})
+}
+
+func (r *Multi) ZRemRangeByScore(ctx context.Context, key, min, max string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZRemRangeByScore(ctx, key, min, max)
+	})
+}
+
+func (r *Multi) ZRevRange(ctx context.Context, key string, start, stop int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRevRange", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRevRange(ctx, key, start, stop)
+	})
+}
+
+func (r *Multi) ZRevRangeByLex(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRevRangeByLex", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRevRangeByLex(ctx, key, min, max, offset, count)
+	})
+}
+
+func (r *Multi) ZRevRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]rueidis.RedisMessage, error) {
+	return readAll(ctx, r, "ZRevRangeByScore", func(ctx context.Context, conn *Redis) ([]rueidis.RedisMessage, error) {
+		return conn.ZRevRangeByScore(ctx, key, min, max, offset, count)
+	})
+}
+
+func (r *Multi) ZRevRank(ctx context.Context, key, member string) (int64, error) {
+	return readAll(ctx, r, "ZRevRank", func(ctx context.Context, conn *Redis) (int64, error) {
+		return conn.ZRevRank(ctx, key, member)
+	})
+}
+
+func (r *Multi) ZScore(ctx context.Context, key, member string) (float64, error) {
+	return readAll(ctx, r, "ZScore", func(ctx context.Context, conn *Redis) (float64, error) {
+		return conn.ZScore(ctx, key, member)
+	})
+}
+
+func (r *Multi) ZUnionStore(ctx context.Context, destination string, keys ...string) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZUnionStore(ctx, destination, keys...)
+	})
+}
+
+func (r *Multi) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return readAll(ctx, r, "Keys", func(ctx context.Context, conn *Redis) ([]string, error) {
+		return conn.Keys(ctx, pattern)
+	})
+}
+
+func (r *Multi) Scan(ctx context.Context, cursor uint64, match string, count int64) (uint64, []string, error) {
+	result, err := readAll(ctx, r, "Scan", func(ctx context.Context, conn *Redis) (scanResult, error) {
+		next, elements, err := conn.Scan(ctx, cursor, match, count)
+		return scanResult{cursor: next, elements: elements}, err
+	})
+	return result.cursor, result.elements, err
+}
+
+func (r *Multi) ScanAllKeys(ctx context.Context, match string, count int64) (map[string]struct{}, error) {
+	return readAll(ctx, r, "ScanAllKeys", func(ctx context.Context, conn *Redis) (map[string]struct{}, error) {
+		return conn.ScanAllKeys(ctx, match, count)
+	})
+}
+
+func (r *Multi) ScanAllFields(ctx context.Context, key string, fieldMatch string, count int64) ([]string, error) {
+	return readAll(ctx, r, "ScanAllFields", func(ctx context.Context, conn *Redis) ([]string, error) {
+		return conn.ScanAllFields(ctx, key, fieldMatch, count)
+	})
+}
+
+func (r *Multi) SScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
+	result, err := readAll(ctx, r, "SScan", func(ctx context.Context, conn *Redis) (scanResult, error) {
+		next, elements, err := conn.SScan(ctx, key, cursor, match, count)
+		return scanResult{cursor: next, elements: elements}, err
+	})
+	return result.cursor, result.elements, err
+}
+
+func (r *Multi) HScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
+	result, err := readAll(ctx, r, "HScan", func(ctx context.Context, conn *Redis) (scanResult, error) {
+		next, elements, err := conn.HScan(ctx, key, cursor, match, count)
+		return scanResult{cursor: next, elements: elements}, err
+	})
+	return result.cursor, result.elements, err
+}
+
+func (r *Multi) ZScan(ctx context.Context, key string, cursor uint64, match string, count int64) (uint64, []string, error) {
+	result, err := readAll(ctx, r, "ZScan", func(ctx context.Context, conn *Redis) (scanResult, error) {
+		next, elements, err := conn.ZScan(ctx, key, cursor, match, count)
+		return scanResult{cursor: next, elements: elements}, err
+	})
+	return result.cursor, result.elements, err
+}
+
+func (r *Multi) GetClient() rueidis.Client {
+	return r.mainConn.GetClient()
+}
+
+func (r *Multi) CTHmget(key string, fields ...string) rueidis.CacheableTTL {
+	return r.mainConn.CTHmget(key, fields...)
+}
+
+func (r *Multi) CTGet(key string) rueidis.CacheableTTL {
+	return r.mainConn.CTGet(key)
+}
+
+func (r *Multi) DoMultiCache(ctx context.Context, commands ...rueidis.CacheableTTL) []rueidis.RedisResult {
+	return r.mainConn.DoMultiCache(ctx, commands...)
+}
+
+func (r *Multi) DoCache(ctx context.Context, cmd rueidis.CacheableTTL) rueidis.RedisResult {
+	return r.mainConn.DoCache(ctx, cmd)
+}
+
+func (r *Multi) GetCompleted(key string) rueidis.Completed {
+	return r.mainConn.GetCompleted(key)
+}
+
+func (r *Multi) HMGetCompleted(ctx context.Context, key string, fields ...string) rueidis.Completed {
+	return r.mainConn.HMGetCompleted(ctx, key, fields...)
+}
+
+// DoMulti sends multi in one pipeline. A pipeline with at least one
+// write is sent to every connection like DoMultiExec, the results
+// of the first connection that failed or of the main connection
+// are returned. Read-only pipelines use the main connection.
+//
+// DoMulti used to take rueidis.Commands, it is variadic now to
+// match Redis.DoMulti and the Client interface: pass the slice as
+// DoMulti(ctx, multi...).
+func (r *Multi) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	if readOnly(multi) {
+		return r.mainConn.DoMulti(ctx, multi...)
+	}
+	for idx := len(r.conn) - 1; idx > 0; idx-- {
+		conn := r.conn[idx]
+		if result := conn.DoMulti(ctx, rebuild(conn, multi)...); HasError(result) != nil {
+			return result
 		}
 	}
-
-	return "", err
+	return r.mainConn.DoMulti(ctx, multi...)
 }
 
-func (r *Multi) Expire(ctx context.Context, key string, ttl time.Duration) (err error) {
-	for _, conn := range r.conn {
-		if err = conn.Expire(ctx, key, ttl); err != nil {
-			return err
+func readOnly(multi rueidis.Commands) bool {
+	for idx := range multi {
+		if !multi[idx].IsReadOnly() {
+			return false
 		}
 	}
+	return true
+}
 
-	return nil
+func (r *Multi) ScanEntryFields(ctx context.Context, key string, fieldMatch string, cursor uint64, count int64) (*rueidis.ScanEntry, error) {
+	return readAll(ctx, r, "ScanEntryFields", func(ctx context.Context, conn *Redis) (*rueidis.ScanEntry, error) {
+		return conn.ScanEntryFields(ctx, key, fieldMatch, cursor, count)
+	})
+}
+
+func (r *Multi) HGetAllCompleted(ctx context.Context, key string) rueidis.Completed {
+	return r.mainConn.HGetAllCompleted(ctx, key)
+}
+
+func (r *Multi) HotKeys() *HotKeys {
+	return r.mainConn.HotKeys()
 }
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const _defaultReplayConcurrency = 100
+
+// ReplayConfig configures Replay. Speed 2 replays twice as fast as
+// recorded, 0 sends the commands without waiting. Concurrency
+// limits the commands in flight.
+type ReplayConfig struct {
+	Speed       float64
+	Concurrency int
+}
+
+// ReplayStats counts the replayed commands. Skipped are the
+// partial writes, see Recording.
+type ReplayStats struct {
+	Sent    int64
+	Failed  int64
+	Skipped int64
+}
+
+// Replay re-issues the commands of a recording written by a
+// Recorder, keeping their relative timing scaled by Speed.
+// Redacted arguments of reads are sent as "?", writes with
+// redacted or truncated arguments are skipped, so the replay
+// doesn't write values that were never written. Commands are sent
+// concurrently, their errors are counted and don't stop the
+// replay.
+func (r *Redis) Replay(ctx context.Context, reader io.Reader, cfg ReplayConfig) (ReplayStats, error) {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = _defaultReplayConcurrency
+	}
+
+	var (
+		sent, failed atomic.Int64
+		skipped      int64
+		wg           sync.WaitGroup
+		inFlight     = make(chan struct{}, cfg.Concurrency)
+		first        time.Time
+		startTime    = time.Now()
+	)
+	stats := func() ReplayStats {
+		wg.Wait()
+		return ReplayStats{Sent: sent.Load(), Failed: failed.Load(), Skipped: skipped}
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Recording
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return stats(), fmt.Errorf("Ошибка чтения записи %d: %w", line, err)
+		}
+		if len(entry.Command) == 0 {
+			continue
+		}
+		if entry.Partial {
+			skipped++
+			continue
+		}
+		args, err := entry.Args()
+		if err != nil {
+			return stats(), fmt.Errorf("Ошибка чтения записи %d: %w", line, err)
+		}
+
+		if first.IsZero() {
+			first = entry.Time
+		}
+		if cfg.Speed > 0 {
+			due := startTime.Add(time.Duration(float64(entry.Time.Sub(first)) / cfg.Speed))
+			if wait := time.Until(due); wait > 0 && !sleep(ctx, wait) {
+				return stats(), ctx.Err()
+			}
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return stats(), ctx.Err()
+		}
+		cmd := r.conn.B().Arbitrary(args...).Build()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+
+			sent.Add(1)
+			if err := r.do(ctx, cmd).Error(); err != nil && !rueidis.IsRedisNil(err) {
+				failed.Add(1)
+			}
+		}()
+	}
+	return stats(), scanner.Err()
+}
@@ -0,0 +1,102 @@
+package redis
+
+import "github.com/redis/rueidis"
+
+// reply is the result of a command sent by the client. err is set
+// when the command failed in the client, e.g. it was not sent or
+// the durability target wasn't reached, and takes precedence over
+// the result.
+type reply struct {
+	result rueidis.RedisResult
+	err    error
+}
+
+func replyValue[T any](r reply, get func(rueidis.RedisResult) (T, error)) (T, error) {
+	if r.err != nil {
+		var empty T
+		return empty, r.err
+	}
+	return get(r.result)
+}
+
+func (r reply) Error() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.result.Error()
+}
+
+func (r reply) NonRedisError() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.result.NonRedisError()
+}
+
+func (r reply) ToMessage() (rueidis.RedisMessage, error) {
+	return replyValue(r, rueidis.RedisResult.ToMessage)
+}
+
+func (r reply) ToString() (string, error) {
+	return replyValue(r, rueidis.RedisResult.ToString)
+}
+
+func (r reply) ToInt64() (int64, error) {
+	return replyValue(r, rueidis.RedisResult.ToInt64)
+}
+
+func (r reply) ToFloat64() (float64, error) {
+	return replyValue(r, rueidis.RedisResult.ToFloat64)
+}
+
+func (r reply) ToBool() (bool, error) {
+	return replyValue(r, rueidis.RedisResult.ToBool)
+}
+
+func (r reply) ToArray() ([]rueidis.RedisMessage, error) {
+	return replyValue(r, rueidis.RedisResult.ToArray)
+}
+
+func (r reply) ToMap() (map[string]rueidis.RedisMessage, error) {
+	return replyValue(r, rueidis.RedisResult.ToMap)
+}
+
+func (r reply) ToAny() (any, error) {
+	return replyValue(r, rueidis.RedisResult.ToAny)
+}
+
+func (r reply) AsInt64() (int64, error) {
+	return replyValue(r, rueidis.RedisResult.AsInt64)
+}
+
+func (r reply) AsFloat64() (float64, error) {
+	return replyValue(r, rueidis.RedisResult.AsFloat64)
+}
+
+func (r reply) AsBool() (bool, error) {
+	return replyValue(r, rueidis.RedisResult.AsBool)
+}
+
+func (r reply) AsStrSlice() ([]string, error) {
+	return replyValue(r, rueidis.RedisResult.AsStrSlice)
+}
+
+func (r reply) AsIntSlice() ([]int64, error) {
+	return replyValue(r, rueidis.RedisResult.AsIntSlice)
+}
+
+func (r reply) AsStrMap() (map[string]string, error) {
+	return replyValue(r, rueidis.RedisResult.AsStrMap)
+}
+
+func (r reply) AsZScores() ([]rueidis.ZScore, error) {
+	return replyValue(r, rueidis.RedisResult.AsZScores)
+}
+
+func (r reply) AsScanEntry() (rueidis.ScanEntry, error) {
+	return replyValue(r, rueidis.RedisResult.AsScanEntry)
+}
+
+func (r reply) AsGeosearch() ([]rueidis.GeoLocation, error) {
+	return replyValue(r, rueidis.RedisResult.AsGeosearch)
+}
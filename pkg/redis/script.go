@@ -0,0 +1,164 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Script is a named Lua script. It is sent with EVALSHA and falls
+// back to EVAL when the server doesn't have it cached.
+type Script struct {
+	name string
+	src  string
+	sha  string
+	lua  *rueidis.Lua
+}
+
+// _scripts are the scripts by sha, so pipelines can resend
+// EVALSHA of a script as EVAL.
+var _scripts sync.Map
+
+func NewScript(name, src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	s := &Script{
+		name: name,
+		src:  src,
+		sha:  hex.EncodeToString(sum[:]),
+		lua:  rueidis.NewLuaScript(src),
+	}
+	_scripts.Store(s.sha, s)
+	return s
+}
+
+// completed builds EVALSHA of the script for a pipeline. Pipelines
+// of Redis resend it as EVAL when it fails with NOSCRIPT.
+func (s *Script) completed(r *Redis, keys, args []string) rueidis.Completed {
+	return r.conn.B().Evalsha().Sha1(s.sha).Numkeys(int64(len(keys))).Key(keys...).Arg(args...).Build()
+}
+
+// pipeliner is implemented by rueidis.Client and
+// rueidis.DedicatedClient.
+type pipeliner interface {
+	B() rueidis.Builder
+	DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult
+}
+
+// evalshas are the tokens of the EVALSHA commands of scripts in a
+// pipeline by index. They are kept before the pipeline is sent,
+// since sent commands are recycled.
+type evalshas map[int][]string
+
+func captureEvalshas(multi rueidis.Commands) evalshas {
+	var captured evalshas
+	for idx := range multi {
+		tokens := multi[idx].Commands()
+		if len(tokens) < 3 || tokens[0] != "EVALSHA" {
+			continue
+		}
+		if _, ok := _scripts.Load(tokens[1]); !ok {
+			continue
+		}
+		if captured == nil {
+			captured = make(evalshas)
+		}
+		captured[idx] = slices.Clone(tokens)
+	}
+	return captured
+}
+
+// noScript returns the indexes of the captured commands that
+// failed with NOSCRIPT, since the server lost its script cache on
+// a restart, failover or SCRIPT FLUSH, and EVAL commands to resend
+// them.
+func (e evalshas) noScript(b rueidis.Builder, results []rueidis.RedisResult) ([]int, rueidis.Commands) {
+	var (
+		indexes []int
+		evals   rueidis.Commands
+	)
+	for idx, tokens := range e {
+		redisErr, ok := rueidis.IsRedisErr(results[idx].Error())
+		if !ok || !redisErr.IsNoScript() {
+			continue
+		}
+		script, _ := _scripts.Load(tokens[1])
+		numKeys, err := strconv.Atoi(tokens[2])
+		if err != nil || 3+numKeys > len(tokens) {
+			continue
+		}
+		indexes = append(indexes, idx)
+		evals = append(evals, b.Arbitrary("EVAL", script.(*Script).src, tokens[2]).
+			Keys(tokens[3:3+numKeys]...).Args(tokens[3+numKeys:]...).Build())
+	}
+	return indexes, evals
+}
+
+// resend sends the commands that failed with NOSCRIPT again as
+// EVAL on client and puts their results into results.
+func (e evalshas) resend(ctx context.Context, client pipeliner, results []rueidis.RedisResult) []rueidis.RedisResult {
+	if len(e) == 0 {
+		return results
+	}
+	indexes, evals := e.noScript(client.B(), results)
+	if len(evals) == 0 {
+		return results
+	}
+	for i, result := range client.DoMulti(ctx, evals...) {
+		results[indexes[i]] = result
+	}
+	return results
+}
+
+// load caches the scripts on every node.
+func (r *Redis) load(ctx context.Context, scripts ...*Script) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	for _, node := range r.conn.Nodes() {
+		for _, script := range scripts {
+			if err := node.Do(ctx, node.B().ScriptLoad().Script(script.src).Build()).Error(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Eval runs the script under the default policy timeout and writes
+// the metric "redis_script_<name>".
+func (r *Redis) Eval(ctx context.Context, script *Script, keys, args []string) rueidis.RedisResult {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	startTime := time.Now()
+	r.addInFlight(1)
+	result := script.lua.Exec(ctx, r.conn, keys, args)
+	r.addInFlight(-1)
+
+	err := result.Error()
+	r.writeTimingAndCounter(startTime, "redis_script_"+script.name, err == nil || rueidis.IsRedisNil(err))
+
+	return result
+}
+
+// Eval runs the script on every connection as a write and returns
+// the result of the main connection.
+func (r *Multi) Eval(ctx context.Context, script *Script, keys, args []string) rueidis.RedisResult {
+	var result rueidis.RedisResult
+	for idx, conn := range r.conn {
+		value := conn.Eval(ctx, script, keys, args)
+		if err := value.Error(); err != nil && !rueidis.IsRedisNil(err) {
+			return value
+		}
+		if idx == 0 {
+			result = value
+		}
+	}
+	return result
+}
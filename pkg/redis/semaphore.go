@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultSemaphoreLease = 30 * time.Second
+	_defaultSemaphoreRetry = 100 * time.Millisecond
+)
+
+var ErrPermitLost = errors.New("semaphore permit is lost")
+
+// _semaphoreAcquireScript removes holders with an expired lease and
+// adds the holder when there is a free slot. Leases use the server
+// clock, so the clocks of instances don't matter. It returns 1 or 0
+// and the number of holders.
+var _semaphoreAcquireScript = NewScript("semaphore_acquire", `
+local time = redis.call('TIME')
+local now = time[1] * 1000 + math.floor(time[2] / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+local holders = redis.call('ZCARD', KEYS[1])
+if holders >= tonumber(ARGV[2]) then
+	return {0, holders}
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {1, holders + 1}
+`)
+
+// _semaphoreRefreshScript extends the lease of a holder that
+// still has one.
+var _semaphoreRefreshScript = NewScript("semaphore_refresh", `
+local time = redis.call('TIME')
+local now = time[1] * 1000 + math.floor(time[2] / 1000)
+local deadline = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not deadline or tonumber(deadline) <= now then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// _semaphoreHoldersScript removes holders with an expired lease
+// and returns the number of the others.
+var _semaphoreHoldersScript = NewScript("semaphore_holders", `
+local time = redis.call('TIME')
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', time[1] * 1000 + math.floor(time[2] / 1000))
+return redis.call('ZCARD', KEYS[1])
+`)
+
+// semaphoreMetrics is implemented by metrics that export the
+// number of holders of a semaphore.
+type semaphoreMetrics interface {
+	SetHolders(semaphore string, holders int64)
+}
+
+type SemaphoreConfig struct {
+	Name          string        `yaml:"name"`
+	Limit         int64         `yaml:"limit"`
+	LeaseTTL      time.Duration `yaml:"lease_ttl"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+// Semaphore limits the number of concurrent holders across
+// instances. Holders are kept in a sorted set by lease deadline, a
+// permit renews its lease until it is released, so the slot of a
+// crashed holder is freed after LeaseTTL.
+type Semaphore struct {
+	redis   *Redis
+	cfg     SemaphoreConfig
+	metrics semaphoreMetrics
+	key     string
+}
+
+func NewSemaphore(r *Redis, cfg SemaphoreConfig, metrics semaphoreMetrics) *Semaphore {
+	if cfg.Limit <= 0 {
+		cfg.Limit = 1
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = _defaultSemaphoreLease
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = _defaultSemaphoreRetry
+	}
+
+	return &Semaphore{
+		redis:   r,
+		cfg:     cfg,
+		metrics: metrics,
+		key:     "semaphore:" + cfg.Name,
+	}
+}
+
+// Acquire waits for a free slot until ctx is done.
+func (s *Semaphore) Acquire(ctx context.Context) (*Permit, error) {
+	for {
+		permit, err := s.TryAcquire(ctx)
+		if err != nil || permit != nil {
+			return permit, err
+		}
+
+		retry := s.cfg.RetryInterval/2 + rand.N(s.cfg.RetryInterval/2+1)
+		if !sleep(ctx, retry) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryAcquire takes a free slot. The permit is nil when all slots
+// are taken.
+func (s *Semaphore) TryAcquire(ctx context.Context) (*Permit, error) {
+	id := instanceID()
+	values, err := s.redis.Eval(ctx, _semaphoreAcquireScript,
+		[]string{s.key},
+		[]string{id, strconv.FormatInt(s.cfg.Limit, 10), strconv.FormatInt(s.cfg.LeaseTTL.Milliseconds(), 10)},
+	).AsIntSlice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 2 {
+		s.setMetric(values[1])
+	}
+	if len(values) == 0 || values[0] == 0 {
+		return nil, nil
+	}
+
+	return newPermit(s, id), nil
+}
+
+// Holders removes expired holders and returns the number of the
+// others.
+func (s *Semaphore) Holders(ctx context.Context) (int64, error) {
+	holders, err := s.redis.Eval(ctx, _semaphoreHoldersScript, []string{s.key}, nil).AsInt64()
+	if err == nil {
+		s.setMetric(holders)
+	}
+	return holders, err
+}
+
+func (s *Semaphore) setMetric(holders int64) {
+	if s.metrics != nil {
+		s.metrics.SetHolders(s.cfg.Name, holders)
+	}
+}
+
+// Permit is a taken slot of a Semaphore. Its lease is renewed in
+// the background until Release, Context is done when the lease is
+// lost or released.
+type Permit struct {
+	semaphore *Semaphore
+	id        string
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	once      sync.Once
+	done      chan struct{}
+}
+
+func newPermit(s *Semaphore, id string) *Permit {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	p := &Permit{
+		semaphore: s,
+		id:        id,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go p.renew()
+	return p
+}
+
+// Context is done when the permit is released or its lease is
+// lost, context.Cause is ErrPermitLost then.
+func (p *Permit) Context() context.Context {
+	return p.ctx
+}
+
+// Release frees the slot.
+func (p *Permit) Release(ctx context.Context) error {
+	var err error
+	p.once.Do(func() {
+		p.cancel(context.Canceled)
+		<-p.done
+
+		if _, err = p.semaphore.redis.ZRem(ctx, p.semaphore.key, p.id); err == nil {
+			_, err = p.semaphore.Holders(ctx)
+		}
+	})
+	return err
+}
+
+func (p *Permit) renew() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.semaphore.cfg.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := p.semaphore.redis.Eval(p.ctx, _semaphoreRefreshScript,
+			[]string{p.semaphore.key},
+			[]string{p.id, strconv.FormatInt(p.semaphore.cfg.LeaseTTL.Milliseconds(), 10)},
+		).AsBool()
+		if err != nil {
+			if p.ctx.Err() == nil {
+				slog.Error("Ошибка продления разрешения семафора", "error", err, "semaphore", p.semaphore.cfg.Name)
+			}
+			continue
+		}
+		if !held {
+			slog.Warn("Разрешение семафора потеряно", "semaphore", p.semaphore.cfg.Name)
+			p.cancel(ErrPermitLost)
+			return
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultSessionTTL     = 30 * time.Minute
+	_sessionIDBytes        = 32
+	_sessionDataPrefix     = "d:"
+	_sessionFieldUser      = "_user"
+	_sessionFieldVersion   = "_ver"
+	_sessionFieldCreatedAt = "_created"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session is not found")
+	ErrSessionConflict = errors.New("session is updated concurrently")
+)
+
+// _sessionTouchLua extends the TTL of the session KEYS[1] with the
+// id ARGV[2] to ARGV[1] ms and its deadline in the index KEYS[2].
+// The index lives as long as its newest session.
+const _sessionTouchLua = `
+local time = redis.call('TIME')
+local now = time[1] * 1000 + math.floor(time[2] / 1000)
+local ttl = tonumber(ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('ZADD', KEYS[2], now + ttl, ARGV[2])
+if redis.call('PTTL', KEYS[2]) < ttl then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+`
+
+// _sessionIndexedLua stops a script when the session KEYS[1] with
+// the id ARGV[2] doesn't belong to the user ARGV[3] or is no longer
+// in the index KEYS[2], e.g. it was evicted and its key is about
+// to be deleted.
+const _sessionIndexedLua = `
+if redis.call('HGET', KEYS[1], '_user') ~= ARGV[3] or not redis.call('ZSCORE', KEYS[2], ARGV[2]) then
+	return 0
+end
+`
+
+// _sessionCreateScript writes a new session and adds it to the
+// index of its user, removing expired entries. Above ARGV[4]
+// sessions the least recently touched ones are removed from the
+// index. It returns the creation time followed by the ids of the
+// removed sessions, whose keys the caller deletes, or -1 when the
+// id is taken.
+var _sessionCreateScript = NewScript("session_create", `
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return {-1}
+end
+redis.call('HSET', KEYS[1], '_user', ARGV[3], '_ver', 1, unpack(ARGV, 5))
+`+_sessionTouchLua+`
+redis.call('HSET', KEYS[1], '_created', now)
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now)
+local result = {now}
+local limit = tonumber(ARGV[4])
+if limit > 0 then
+	local evicted = redis.call('ZCARD', KEYS[2]) - limit
+	if evicted > 0 then
+		for _, id in ipairs(redis.call('ZRANGE', KEYS[2], 0, evicted - 1)) do
+			table.insert(result, id)
+		end
+		redis.call('ZREMRANGEBYRANK', KEYS[2], 0, evicted - 1)
+	end
+end
+return result
+`)
+
+// _sessionTouchScript extends the TTL of an existing session. It
+// returns 0 when the session is not found.
+var _sessionTouchScript = NewScript("session_touch", _sessionIndexedLua+_sessionTouchLua+`
+return 1
+`)
+
+// _sessionSaveScript replaces the data of the session when its
+// version is ARGV[4] and touches it. It returns the new version,
+// 0 when the session is not found and -1 when the version has
+// changed.
+var _sessionSaveScript = NewScript("session_save", _sessionIndexedLua+`
+if redis.call('HGET', KEYS[1], '_ver') ~= ARGV[4] then
+	return -1
+end
+for _, field in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if string.sub(field, 1, 2) == 'd:' then
+		redis.call('HDEL', KEYS[1], field)
+	end
+end
+if #ARGV > 4 then
+	redis.call('HSET', KEYS[1], unpack(ARGV, 5))
+end
+`+_sessionTouchLua+`
+return redis.call('HINCRBY', KEYS[1], '_ver', 1)
+`)
+
+// _sessionLoadScript returns the fields of the session KEYS[1]
+// with the id ARGV[1], or none when it is no longer in the index
+// KEYS[2] or its deadline there has passed.
+var _sessionLoadScript = NewScript("session_load", `
+local deadline = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if not deadline then
+	return {}
+end
+local time = redis.call('TIME')
+if tonumber(deadline) <= time[1] * 1000 + math.floor(time[2] / 1000) then
+	return {}
+end
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// _sessionDestroyScript deletes the session KEYS[1] of the user
+// ARGV[2] and removes it from the index KEYS[2]. It returns 0 when
+// the session is not found.
+var _sessionDestroyScript = NewScript("session_destroy", `
+if redis.call('HGET', KEYS[1], '_user') ~= ARGV[2] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return 1
+`)
+
+// _sessionDestroyUserScript deletes the index KEYS[1] and returns
+// the ids of its sessions, whose keys the caller deletes.
+var _sessionDestroyUserScript = NewScript("session_destroy_user", `
+local ids = redis.call('ZRANGE', KEYS[1], 0, -1)
+redis.call('DEL', KEYS[1])
+return ids
+`)
+
+// _sessionRotateScript moves the session KEYS[1] with the id
+// ARGV[2] to KEYS[3] with the id ARGV[1] keeping its data, TTL
+// and deadline in the index KEYS[2]. It returns 0 when the session
+// is not found.
+var _sessionRotateScript = NewScript("session_rotate", _sessionIndexedLua+`
+redis.call('RENAME', KEYS[1], KEYS[3])
+local deadline = redis.call('ZSCORE', KEYS[2], ARGV[2])
+redis.call('ZREM', KEYS[2], ARGV[2])
+redis.call('ZADD', KEYS[2], deadline, ARGV[1])
+return 1
+`)
+
+type SessionConfig struct {
+	Name       string        `yaml:"name"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxPerUser int64         `yaml:"max_per_user"`
+}
+
+// Session is a web session. Version changes on every save, a save
+// of a stale version fails with ErrSessionConflict.
+type Session struct {
+	ID        string
+	UserID    string
+	Data      map[string]string
+	CreatedAt time.Time
+	Version   int64
+}
+
+// SessionStore keeps sessions in hashes with a sliding TTL. The
+// sessions of a user are indexed in a sorted set by expiry, so a
+// user can be signed out everywhere, and the least recently
+// touched sessions above MaxPerUser are destroyed on create. All
+// keys of a store share the hash tag of its name, so scripts can
+// update a session and its index atomically in a cluster.
+type SessionStore struct {
+	redis       *Redis
+	cfg         SessionConfig
+	prefix      string
+	indexPrefix string
+}
+
+func NewSessionStore(r *Redis, cfg SessionConfig) *SessionStore {
+	if cfg.TTL <= 0 {
+		cfg.TTL = _defaultSessionTTL
+	}
+
+	return &SessionStore{
+		redis:       r,
+		cfg:         cfg,
+		prefix:      "session:{" + cfg.Name + "}:id:",
+		indexPrefix: "session:{" + cfg.Name + "}:user:",
+	}
+}
+
+// Create starts a session of the user.
+func (s *SessionStore) Create(ctx context.Context, userID string, data map[string]string) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{s.ttl(), id, userID, strconv.FormatInt(s.cfg.MaxPerUser, 10)}, dataArgs(data)...)
+	values, err := s.redis.Eval(ctx, _sessionCreateScript, []string{s.prefix + id, s.indexPrefix + userID}, args).ToArray()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errors.New("session id is taken")
+	}
+	createdAt, err := values[0].AsInt64()
+	if err != nil {
+		return nil, err
+	}
+	if createdAt < 0 {
+		return nil, errors.New("session id is taken")
+	}
+
+	evicted := make([]string, 0, len(values)-1)
+	for _, value := range values[1:] {
+		evictedID, err := value.ToString()
+		if err != nil {
+			return nil, err
+		}
+		evicted = append(evicted, evictedID)
+	}
+	if err := s.delete(ctx, evicted); err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ID:        id,
+		UserID:    userID,
+		Data:      data,
+		CreatedAt: time.UnixMilli(createdAt),
+		Version:   1,
+	}, nil
+}
+
+// Load returns the session without touching it. Sessions removed
+// from the index of their user, e.g. evicted or signed out, are
+// not found even before their keys are deleted.
+func (s *SessionStore) Load(ctx context.Context, id string) (*Session, error) {
+	userID, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := s.redis.Eval(ctx, _sessionLoadScript,
+		[]string{s.prefix + id, s.indexPrefix + userID},
+		[]string{id},
+	).AsStrMap()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	session := &Session{
+		ID:     id,
+		UserID: fields[_sessionFieldUser],
+		Data:   make(map[string]string, len(fields)),
+	}
+	if session.Version, err = strconv.ParseInt(fields[_sessionFieldVersion], 10, 64); err != nil {
+		return nil, err
+	}
+	createdAt, err := strconv.ParseInt(fields[_sessionFieldCreatedAt], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	session.CreatedAt = time.UnixMilli(createdAt)
+	for field, value := range fields {
+		if name, ok := strings.CutPrefix(field, _sessionDataPrefix); ok {
+			session.Data[name] = value
+		}
+	}
+
+	return session, nil
+}
+
+// Touch extends the TTL of the session.
+func (s *SessionStore) Touch(ctx context.Context, id string) error {
+	userID, err := s.user(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	touched, err := s.redis.Eval(ctx, _sessionTouchScript,
+		[]string{s.prefix + id, s.indexPrefix + userID},
+		[]string{s.ttl(), id, userID},
+	).AsInt64()
+	if err != nil {
+		return err
+	}
+	if touched == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Save replaces the data of the session and touches it. It fails
+// with ErrSessionConflict when the session was saved since it was
+// loaded, the session has to be loaded again then.
+func (s *SessionStore) Save(ctx context.Context, session *Session) error {
+	args := append([]string{s.ttl(), session.ID, session.UserID, strconv.FormatInt(session.Version, 10)}, dataArgs(session.Data)...)
+	version, err := s.redis.Eval(ctx, _sessionSaveScript, []string{s.prefix + session.ID, s.indexPrefix + session.UserID}, args).AsInt64()
+	if err != nil {
+		return err
+	}
+
+	switch version {
+	case 0:
+		return ErrSessionNotFound
+	case -1:
+		return ErrSessionConflict
+	}
+	session.Version = version
+	return nil
+}
+
+// Destroy deletes the session.
+func (s *SessionStore) Destroy(ctx context.Context, id string) error {
+	userID, err := s.user(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	destroyed, err := s.redis.Eval(ctx, _sessionDestroyScript,
+		[]string{s.prefix + id, s.indexPrefix + userID},
+		[]string{id, userID},
+	).AsInt64()
+	if err != nil {
+		return err
+	}
+	if destroyed == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DestroyUser signs the user out everywhere and returns the
+// number of destroyed sessions.
+func (s *SessionStore) DestroyUser(ctx context.Context, userID string) (int64, error) {
+	ids, err := s.redis.Eval(ctx, _sessionDestroyUserScript, []string{s.indexPrefix + userID}, nil).AsStrSlice()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.delete(ctx, ids); err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// Rotate moves the session to a new id, e.g. after sign-in, and
+// updates session.ID. The old id stops working.
+func (s *SessionStore) Rotate(ctx context.Context, session *Session) error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	rotated, err := s.redis.Eval(ctx, _sessionRotateScript,
+		[]string{s.prefix + session.ID, s.indexPrefix + session.UserID, s.prefix + id},
+		[]string{id, session.ID, session.UserID},
+	).AsInt64()
+	if err != nil {
+		return err
+	}
+	if rotated == 0 {
+		return ErrSessionNotFound
+	}
+
+	session.ID = id
+	return nil
+}
+
+// Sessions returns the ids of the sessions of the user that have
+// not expired.
+func (s *SessionStore) Sessions(ctx context.Context, userID string) ([]string, error) {
+	startTime := time.Now()
+	cmd := s.redis.conn.B().Zrangebyscore().Key(s.indexPrefix + userID).
+		Min("(" + strconv.FormatInt(time.Now().UnixMilli(), 10)).Max("+inf").Build()
+	ids, err := s.redis.do(ctx, cmd).AsStrSlice()
+	s.redis.writeTimingAndCounter(startTime, "redis_session_list", err == nil)
+
+	return ids, err
+}
+
+// user returns the owner of the session, so scripts can declare
+// the key of its index.
+func (s *SessionStore) user(ctx context.Context, id string) (string, error) {
+	startTime := time.Now()
+	userID, err := s.redis.do(ctx, s.redis.conn.B().Hget().Key(s.prefix+id).Field(_sessionFieldUser).Build()).ToString()
+	s.redis.writeTimingAndCounter(startTime, "redis_session_user", err == nil || rueidis.IsRedisNil(err))
+	if rueidis.IsRedisNil(err) {
+		return "", ErrSessionNotFound
+	}
+	return userID, err
+}
+
+// delete removes the keys of sessions already removed from their
+// index. They are no longer touched or saved, so a failed delete
+// leaves them to expire.
+func (s *SessionStore) delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.prefix + id
+	}
+	_, err := s.redis.DelMulti(ctx, keys...)
+	return err
+}
+
+func (s *SessionStore) ttl() string {
+	return strconv.FormatInt(s.cfg.TTL.Milliseconds(), 10)
+}
+
+func dataArgs(data map[string]string) []string {
+	args := make([]string, 0, 2*len(data))
+	for name, value := range data {
+		args = append(args, _sessionDataPrefix+name, value)
+	}
+	return args
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, _sessionIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
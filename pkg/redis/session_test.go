@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// newTestRedis connects to the redis of REDIS_TEST_ADDR, the test
+// is skipped without it.
+func newTestRedis(t *testing.T) *Redis {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	r, err := New(&Config{Hosts: []string{addr}, DisableCache: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+func newTestSessionStore(t *testing.T, maxPerUser int64) *SessionStore {
+	t.Helper()
+	id, err := newSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSessionStore(newTestRedis(t), SessionConfig{Name: "test:" + id, MaxPerUser: maxPerUser})
+}
+
+func TestSessionLoadAfterDestroyUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessionStore(t, 0)
+
+	var ids []string
+	for range 2 {
+		session, err := s.Create(ctx, "user", map[string]string{"k": "v"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, session.ID)
+	}
+
+	destroyed, err := s.DestroyUser(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if destroyed != 2 {
+		t.Errorf("DestroyUser = %d, want 2", destroyed)
+	}
+	for _, id := range ids {
+		if _, err := s.Load(ctx, id); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Load(%s) error = %v, want %v", id, err, ErrSessionNotFound)
+		}
+	}
+}
+
+func TestSessionLoadAfterEviction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessionStore(t, 2)
+
+	var ids []string
+	for range 3 {
+		session, err := s.Create(ctx, "user", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, session.ID)
+	}
+
+	if _, err := s.Load(ctx, ids[0]); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load of the evicted session error = %v, want %v", err, ErrSessionNotFound)
+	}
+	for _, id := range ids[1:] {
+		session, err := s.Load(ctx, id)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", id, err)
+		}
+		if session.UserID != "user" {
+			t.Errorf("UserID = %q, want %q", session.UserID, "user")
+		}
+	}
+}
+
+func TestSessionLoadOutsideIndex(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSessionStore(t, 0)
+
+	session, err := s.Create(ctx, "user", map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.Load(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Data["k"] != "v" || loaded.Version != 1 {
+		t.Errorf("Load = %+v, want the created session", loaded)
+	}
+
+	// The key is left behind as if its delete after an eviction
+	// failed.
+	cmd := s.redis.conn.B().Zrem().Key(s.indexPrefix + "user").Member(session.ID).Build()
+	if err := s.redis.conn.Do(ctx, cmd).Error(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load(ctx, session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load error = %v, want %v", err, ErrSessionNotFound)
+	}
+}
@@ -0,0 +1,346 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultShadowTimeout     = time.Second
+	_defaultShadowMaxInFlight = 1000
+	_defaultShadowLogRate     = 0.01
+	_defaultShadowTTLSlack    = 2 * time.Second
+	_maxShadowLogValue        = 512
+	_shadowMatch              = "match"
+	_shadowMismatch           = "mismatch"
+	_shadowError              = "error"
+	_shadowDropped            = "dropped"
+)
+
+var (
+	// _shadowSkipped reads return random results, or keys and
+	// cursors that depend on the layout of the server, they can't
+	// be compared.
+	_shadowSkipped = map[string]bool{
+		"SRandMember": true, "Keys": true, "Scan": true, "HScan": true,
+		"SScan": true, "ZScan": true, "ScanAllKeys": true,
+		"ScanAllFields": true, "ScanEntryFields": true,
+	}
+	// _shadowUnordered reads return members of a set or fields of a
+	// hash, their order differs between servers.
+	_shadowUnordered = map[string]bool{
+		"SMembers": true, "SInter": true, "SUnion": true, "SDiff": true,
+		"HKeys": true, "HVals": true,
+	}
+)
+
+// shadowMetrics is implemented by metrics that count the outcomes
+// of shadow reads.
+type shadowMetrics interface {
+	IncShadow(command, result string)
+}
+
+// ShadowConfig configures shadow reads of a Multi. SampleRate is
+// the share of reads mirrored to the shadow connection, LogRate is
+// the share of mismatches that are logged. TTL, PTTL and HTTL
+// replies that differ by up to TTLSlack match, since the shadow
+// read runs later.
+type ShadowConfig struct {
+	Redis       Config        `yaml:"redis"`
+	SampleRate  float64       `yaml:"sample_rate"`
+	LogRate     float64       `yaml:"log_rate"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxInFlight int           `yaml:"max_in_flight"`
+	TTLSlack    time.Duration `yaml:"ttl_slack"`
+}
+
+type shadow struct {
+	conn     *Redis
+	cfg      ShadowConfig
+	metrics  shadowMetrics
+	inFlight chan struct{}
+}
+
+// EnableShadow mirrors reads of the main connection to a shadow
+// connection. The shadow read runs in the background after the
+// main read has returned, so callers see no added latency, and
+// its result is compared with the main one. Outcomes go to
+// metrics, a sample of mismatches is logged. Reads above
+// MaxInFlight are dropped. Enabling again replaces the shadow.
+// Only typed reads are mirrored, pipelines, cached reads and
+// SRandMember are not.
+func (r *Multi) EnableShadow(cfg ShadowConfig, metrics shadowMetrics) error {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 1
+	}
+	if cfg.LogRate <= 0 {
+		cfg.LogRate = _defaultShadowLogRate
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = _defaultShadowTimeout
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = _defaultShadowMaxInFlight
+	}
+	if cfg.TTLSlack <= 0 {
+		cfg.TTLSlack = _defaultShadowTTLSlack
+	}
+
+	conn, err := New(&cfg.Redis, nil)
+	if err != nil {
+		return err
+	}
+
+	old := r.shadow.Swap(&shadow{
+		conn:     conn,
+		cfg:      cfg,
+		metrics:  metrics,
+		inFlight: make(chan struct{}, cfg.MaxInFlight),
+	})
+	if old != nil {
+		old.conn.Close()
+	}
+	return nil
+}
+
+// DisableShadow stops shadow reads and closes the shadow
+// connection.
+func (r *Multi) DisableShadow() {
+	if old := r.shadow.Swap(nil); old != nil {
+		old.conn.Close()
+	}
+}
+
+// shadowRead repeats call on the shadow connection in the
+// background and compares its result with the result of the main
+// connection. Failed main reads are not compared. The main result
+// is copied before returning, since the caller owns it afterwards.
+func shadowRead[T any](ctx context.Context, r *Multi, command string, result T, err error, call func(ctx context.Context, conn *Redis) (T, error)) {
+	s := r.shadow.Load()
+	if s == nil || _shadowSkipped[command] || err != nil && !rueidis.IsRedisNil(err) || rand.Float64() >= s.cfg.SampleRate {
+		return
+	}
+
+	select {
+	case s.inFlight <- struct{}{}:
+	default:
+		s.inc(command, _shadowDropped)
+		return
+	}
+
+	main := copyResult(result)
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-s.inFlight }()
+
+		ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+		shadowResult, shadowErr := call(ctx, s.conn)
+
+		switch {
+		case shadowErr != nil && !rueidis.IsRedisNil(shadowErr):
+			s.inc(command, _shadowError)
+		case rueidis.IsRedisNil(err) != rueidis.IsRedisNil(shadowErr),
+			!rueidis.IsRedisNil(err) && !s.match(command, main, shadowResult):
+			s.inc(command, _shadowMismatch)
+			if rand.Float64() < s.cfg.LogRate {
+				slog.Warn("Расхождение теневого чтения", "command", command,
+					"main_nil", rueidis.IsRedisNil(err), "shadow_nil", rueidis.IsRedisNil(shadowErr),
+					"shadow", truncate(fmt.Sprintf("%+v", shadowResult), _maxShadowLogValue))
+			}
+		default:
+			s.inc(command, _shadowMatch)
+		}
+	}()
+}
+
+// match compares the main and shadow results of command.
+func (s *shadow) match(command string, main, shadow any) bool {
+	switch command {
+	case "TTL":
+		return ttlMatch(main, shadow, int64(s.cfg.TTLSlack/time.Second))
+	case "PTTL":
+		return ttlMatch(main, shadow, s.cfg.TTLSlack.Milliseconds())
+	case "HTTL":
+		mainTTLs, _ := main.([]int64)
+		shadowTTLs, _ := shadow.([]int64)
+		slack := int64(s.cfg.TTLSlack / time.Second)
+		return slices.EqualFunc(mainTTLs, shadowTTLs, func(mainTTL, shadowTTL int64) bool {
+			return ttlMatch(mainTTL, shadowTTL, slack)
+		})
+	}
+	unordered := _shadowUnordered[command]
+	return fingerprint(main, unordered) == fingerprint(shadow, unordered)
+}
+
+// ttlMatch compares TTLs with slack, the negative replies for
+// keys without TTL or missing keys must be equal.
+func ttlMatch(main, shadow any, slack int64) bool {
+	mainTTL, _ := main.(int64)
+	shadowTTL, _ := shadow.(int64)
+	if mainTTL < 0 || shadowTTL < 0 {
+		return mainTTL == shadowTTL
+	}
+	return max(mainTTL-shadowTTL, shadowTTL-mainTTL) <= slack
+}
+
+func (s *shadow) inc(command, result string) {
+	if s.metrics != nil {
+		s.metrics.IncShadow(command, result)
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// copyResult copies the slices, maps and pointers of v, so the
+// copy can be read while the caller changes v.
+func copyResult[T any](v T) T {
+	var result T
+	reflect.ValueOf(&result).Elem().Set(copyValue(reflect.ValueOf(&v).Elem()))
+	return result
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for idx := range v.Len() {
+			c.Index(idx).Set(copyValue(v.Index(idx)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return c
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(copyValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for idx := range v.NumField() {
+			// Unexported fields are copied shallowly.
+			if c.Field(idx).CanSet() {
+				c.Field(idx).Set(copyValue(v.Field(idx)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// fingerprint hashes v deeply: pointers are followed, unexported
+// fields are included and maps are hashed regardless of their
+// iteration order. With unordered a slice is hashed regardless of
+// the order of its elements.
+func fingerprint(v any, unordered bool) uint64 {
+	h := fnv.New64a()
+	value := reflect.ValueOf(v)
+	if unordered && value.Kind() == reflect.Slice {
+		writeUint(h, uint64(value.Kind()))
+		writeUnordered(h, value.Len(), func(idx int, entry hash.Hash64) {
+			writeValue(entry, value.Index(idx))
+		})
+	} else {
+		writeValue(h, value)
+	}
+	return h.Sum64()
+}
+
+// writeUnordered hashes n entries regardless of their order.
+func writeUnordered(h hash.Hash64, n int, write func(idx int, entry hash.Hash64)) {
+	entries := make([]uint64, n)
+	for idx := range n {
+		entry := fnv.New64a()
+		write(idx, entry)
+		entries[idx] = entry.Sum64()
+	}
+	slices.Sort(entries)
+	writeUint(h, uint64(n))
+	for _, entry := range entries {
+		writeUint(h, entry)
+	}
+}
+
+func writeUint(h hash.Hash64, u uint64) {
+	var buf [8]byte
+	for idx := range buf {
+		buf[idx] = byte(u >> (8 * idx))
+	}
+	h.Write(buf[:])
+}
+
+func writeValue(h hash.Hash64, v reflect.Value) {
+	if !v.IsValid() {
+		writeUint(h, 0)
+		return
+	}
+	writeUint(h, uint64(v.Kind()))
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(h, 1)
+		} else {
+			writeUint(h, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(h, math.Float64bits(v.Float()))
+	case reflect.String:
+		writeUint(h, uint64(v.Len()))
+		h.Write([]byte(v.String()))
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			writeUint(h, 0)
+			return
+		}
+		writeValue(h, v.Elem())
+	case reflect.Slice, reflect.Array:
+		writeUint(h, uint64(v.Len()))
+		for idx := range v.Len() {
+			writeValue(h, v.Index(idx))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		writeUnordered(h, len(keys), func(idx int, entry hash.Hash64) {
+			writeValue(entry, keys[idx])
+			writeValue(entry, v.MapIndex(keys[idx]))
+		})
+	case reflect.Struct:
+		for idx := range v.NumField() {
+			writeValue(h, v.Field(idx))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name      string
+		a, b      any
+		unordered bool
+		want      bool
+	}{
+		{"equal strings", "a", "a", false, true},
+		{"different strings", "a", "b", false, false},
+		{"string and int", "1", int64(1), false, false},
+		{"nil and empty string", nil, "", false, false},
+		{"equal pointers", str("a"), str("a"), false, true},
+		{"nil pointer", (*string)(nil), str(""), false, false},
+		{"ordered slice", []string{"a", "b"}, []string{"b", "a"}, false, false},
+		{"unordered slice", []string{"a", "b"}, []string{"b", "a"}, true, true},
+		{"unordered duplicates", []string{"a", "a", "b"}, []string{"a", "b", "b"}, true, false},
+		{"split strings", []string{"ab", "c"}, []string{"a", "bc"}, false, false},
+		{"maps", map[string]string{"a": "1", "b": "2"}, map[string]string{"b": "2", "a": "1"}, false, true},
+		{"map values", map[string]string{"a": "1"}, map[string]string{"a": "2"}, false, false},
+		{"nil and empty slice", []string(nil), []string{}, false, true},
+		{"structs", scanResult{cursor: 1, elements: []string{"a"}}, scanResult{cursor: 2, elements: []string{"a"}}, false, false},
+		{"floats", 1.5, 1.5, false, true},
+		{"bools", true, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprint(tt.a, tt.unordered) == fingerprint(tt.b, tt.unordered); got != tt.want {
+				t.Errorf("fingerprint(%v) == fingerprint(%v) is %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShadowMatch(t *testing.T) {
+	s := &shadow{cfg: ShadowConfig{TTLSlack: 2 * time.Second}}
+	tests := []struct {
+		command      string
+		main, shadow any
+		want         bool
+	}{
+		{"TTL", int64(100), int64(98), true},
+		{"TTL", int64(100), int64(97), false},
+		{"TTL", int64(-1), int64(-2), false},
+		{"TTL", int64(-1), int64(1), false},
+		{"PTTL", int64(10000), int64(8000), true},
+		{"PTTL", int64(10000), int64(7999), false},
+		{"HTTL", []int64{100, -1, -2}, []int64{99, -1, -2}, true},
+		{"HTTL", []int64{100, 50}, []int64{100, 47}, false},
+		{"HTTL", []int64{-1}, []int64{-2}, false},
+		{"HTTL", []int64{100}, []int64{100, 100}, false},
+		{"SMembers", []string{"a", "b"}, []string{"b", "a"}, true},
+		{"LRange", []string{"a", "b"}, []string{"b", "a"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := s.match(tt.command, tt.main, tt.shadow); got != tt.want {
+			t.Errorf("match(%s, %v, %v) = %v, want %v", tt.command, tt.main, tt.shadow, got, tt.want)
+		}
+	}
+}
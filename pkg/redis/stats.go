@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// connectionMetrics is implemented by metrics that can tell the
+// connections of a Multi apart.
+type connectionMetrics interface {
+	WriteConnectionTimingAndCounter(startTime time.Time, connection, query string, success bool)
+}
+
+// clientStats is implemented by metrics that collect client-side
+// statistics of a connection.
+type clientStats interface {
+	AddInFlight(connection string, delta int)
+	ObservePipeline(connection string, size int)
+	IncCache(connection string, hit bool)
+	IncReconnect(connection string)
+}
+
+type stats struct {
+	collector clientStats
+
+	mu     sync.Mutex
+	closed map[string]int
+}
+
+func newStats(metrics metrics) *stats {
+	s := &stats{closed: make(map[string]int)}
+	if collector, ok := metrics.(clientStats); ok {
+		s.collector = collector
+	}
+	return s
+}
+
+func (r *Redis) writeTimingAndCounter(startTime time.Time, query string, success bool) {
+	if m, ok := r.metrics.(connectionMetrics); ok {
+		m.WriteConnectionTimingAndCounter(startTime, r.connectionName, query, success)
+		return
+	}
+	if r.metrics != nil {
+		r.metrics.WriteTimingAndCounter(startTime, query, success)
+	}
+}
+
+func (r *Redis) addInFlight(delta int) {
+	if r.stats != nil && r.stats.collector != nil {
+		r.stats.collector.AddInFlight(r.connectionName, delta)
+	}
+}
+
+func (r *Redis) observePipeline(size int) {
+	if r.stats != nil && r.stats.collector != nil {
+		r.stats.collector.ObservePipeline(r.connectionName, size)
+	}
+}
+
+func (r *Redis) observeCache(results ...rueidis.RedisResult) {
+	if r.stats == nil || r.stats.collector == nil {
+		return
+	}
+	for _, result := range results {
+		if result.Error() == nil {
+			r.stats.collector.IncCache(r.connectionName, result.IsCacheHit())
+		}
+	}
+}
+
+// dial opens connections the way rueidis does by default and
+// counts a reconnect when an address is dialed again after one of
+// its connections was closed. New connections, e.g. of the
+// blocking pool, are not reconnects.
+func (r *Redis) dial(dst string, dialer *net.Dialer, tlsConfig *tls.Config) (net.Conn, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", dst, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.stats.redial(dst) && r.stats.collector != nil {
+		r.stats.collector.IncReconnect(r.connectionName)
+	}
+	return &trackedConn{Conn: conn, onClose: func() { r.stats.close(dst) }}, nil
+}
+
+// redial reports whether a closed connection of dst is replaced.
+func (s *stats) redial(dst string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed[dst] == 0 {
+		return false
+	}
+	s.closed[dst]--
+	return true
+}
+
+func (s *stats) close(dst string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed[dst]++
+}
+
+// trackedConn reports its first Close.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
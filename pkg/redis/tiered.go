@@ -0,0 +1,311 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/maphash"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	_defaultTieredSize     = 10000
+	_defaultTieredLocalTTL = time.Minute
+	_tieredGenerations     = 4096
+	_tierLocal             = "local"
+	_tierRedis             = "redis"
+)
+
+// tierMetrics is implemented by metrics that count hits and
+// misses of every tier of a TieredCache.
+type tierMetrics interface {
+	IncTier(cache, tier string, hit bool)
+}
+
+// Codec converts cached values to strings stored in redis.
+type Codec[V any] struct {
+	Encode func(V) (string, error)
+	Decode func(string) (V, error)
+}
+
+// JSONCodec stores values as JSON.
+func JSONCodec[V any]() Codec[V] {
+	return Codec[V]{
+		Encode: func(v V) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		Decode: func(s string) (v V, err error) {
+			err = json.Unmarshal([]byte(s), &v)
+			return v, err
+		},
+	}
+}
+
+// StringCodec stores strings as is.
+func StringCodec() Codec[string] {
+	return Codec[string]{
+		Encode: func(v string) (string, error) { return v, nil },
+		Decode: func(s string) (string, error) { return s, nil },
+	}
+}
+
+type TieredCacheConfig struct {
+	Name     string        `yaml:"name"`
+	Size     int           `yaml:"size"`
+	LocalTTL time.Duration `yaml:"local_ttl"`
+	TTL      time.Duration `yaml:"ttl"`
+	Channel  string        `yaml:"channel"`
+}
+
+// TieredCache is a bounded in-process LRU in front of redis.
+// Writes and deletes are broadcast through redis pub/sub, so
+// every instance drops its stale local entry. TieredCache
+// implements service.ServiceWithDown, the subscription runs
+// between Up and Down.
+type TieredCache[V any] struct {
+	redis    *Redis
+	cfg      TieredCacheConfig
+	codec    Codec[V]
+	local    *lru[V]
+	gens     *generations
+	metrics  tierMetrics
+	instance string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewTieredCache[V any](r *Redis, cfg TieredCacheConfig, codec Codec[V], metrics tierMetrics) *TieredCache[V] {
+	if cfg.Size <= 0 {
+		cfg.Size = _defaultTieredSize
+	}
+	if cfg.LocalTTL <= 0 {
+		cfg.LocalTTL = _defaultTieredLocalTTL
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = r.ttl
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = "tiered-cache:" + cfg.Name
+	}
+
+	return &TieredCache[V]{
+		redis:    r,
+		cfg:      cfg,
+		codec:    codec,
+		local:    newLRU[V](cfg.Size),
+		gens:     newGenerations(),
+		metrics:  metrics,
+		instance: instanceID(),
+	}
+}
+
+// Get returns the value from the local tier or from redis. ok is
+// false when the key is in neither tier.
+func (c *TieredCache[V]) Get(ctx context.Context, key string) (value V, ok bool, err error) {
+	if value, ok = c.local.Get(key); ok {
+		c.inc(_tierLocal, true)
+		return value, true, nil
+	}
+	c.inc(_tierLocal, false)
+
+	// An invalidation applied while GET is in flight bumps the
+	// generation, the value read before it is not kept locally.
+	gen := c.gens.load(key)
+
+	startTime := time.Now()
+	builder := c.redis.conn.B()
+	results := c.redis.DoMulti(ctx, builder.Get().Key(key).Build(), builder.Pttl().Key(key).Build())
+	raw, err := results[0].ToString()
+	c.redis.writeTimingAndCounter(startTime, "redis_tiered_get", err == nil || rueidis.IsRedisNil(err))
+	if rueidis.IsRedisNil(err) {
+		c.inc(_tierRedis, false)
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	c.inc(_tierRedis, true)
+
+	if value, err = c.codec.Decode(raw); err != nil {
+		return value, false, err
+	}
+
+	// The local copy doesn't outlive the redis key.
+	localTTL := c.cfg.LocalTTL
+	if pttl, err := results[1].AsInt64(); err == nil && pttl > 0 {
+		localTTL = min(localTTL, time.Duration(pttl)*time.Millisecond)
+	}
+	if c.gens.load(key) == gen {
+		c.local.SetTTL(key, value, localTTL)
+	}
+
+	return value, true, nil
+}
+
+// GetOrLoad returns the cached value or stores the result of
+// load in both tiers.
+func (c *TieredCache[V]) GetOrLoad(ctx context.Context, key string, load func(context.Context) (V, error)) (V, error) {
+	value, ok, err := c.Get(ctx, key)
+	if err != nil || ok {
+		return value, err
+	}
+
+	if value, err = load(ctx); err != nil {
+		return value, err
+	}
+	return value, c.Set(ctx, key, value)
+}
+
+// Set writes the value to redis, notifies other instances and
+// keeps the value in the local tier.
+func (c *TieredCache[V]) Set(ctx context.Context, key string, value V) error {
+	raw, err := c.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err = c.redis.Set(ctx, key, raw, c.cfg.TTL); err != nil {
+		return err
+	}
+	// Reads that started before the write don't keep the old
+	// value.
+	c.gens.bump(key)
+	localTTL := c.cfg.LocalTTL
+	if c.cfg.TTL > 0 {
+		localTTL = min(localTTL, c.cfg.TTL)
+	}
+	c.local.SetTTL(key, value, localTTL)
+
+	return c.publish(ctx, key)
+}
+
+// Delete removes the key from redis and from the local tier of
+// every instance.
+func (c *TieredCache[V]) Delete(ctx context.Context, key string) error {
+	c.drop(key)
+	if _, err := c.redis.Del(ctx, key); err != nil {
+		return err
+	}
+
+	return c.publish(ctx, key)
+}
+
+// Invalidate drops keys from the local tier of every instance
+// without touching redis.
+func (c *TieredCache[V]) Invalidate(ctx context.Context, keys ...string) error {
+	var errAll error
+	for _, key := range keys {
+		c.drop(key)
+		errAll = errors.Join(errAll, c.publish(ctx, key))
+	}
+	return errAll
+}
+
+func (c *TieredCache[V]) Up(ctx context.Context) error {
+	c.mu.Lock()
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	c.mu.Unlock()
+	defer close(c.done)
+
+	for {
+		cmd := c.redis.conn.B().Subscribe().Channel(c.cfg.Channel).Build()
+		err := c.redis.conn.Receive(ctx, cmd, func(msg rueidis.PubSubMessage) {
+			instance, key, ok := strings.Cut(msg.Message, "|")
+			if ok && instance != c.instance {
+				c.drop(key)
+			}
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			slog.Error("Ошибка подписки на инвалидацию кэша", "error", err, "cache", c.cfg.Name)
+		}
+
+		// Invalidations may have been missed while the subscription
+		// was down.
+		c.gens.bumpAll()
+		c.local.Purge()
+		if !sleep(ctx, _defaultResubscribeWait) {
+			return nil
+		}
+	}
+}
+
+func (c *TieredCache[V]) Down(ctx context.Context) error {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *TieredCache[V]) publish(ctx context.Context, key string) error {
+	startTime := time.Now()
+	cmd := c.redis.conn.B().Publish().Channel(c.cfg.Channel).Message(c.instance + "|" + key).Build()
+	err := c.redis.do(ctx, cmd).Error()
+	c.redis.writeTimingAndCounter(startTime, "redis_publish", err == nil)
+
+	return err
+}
+
+// drop removes the key from the local tier and stops reads in
+// flight from putting it back.
+func (c *TieredCache[V]) drop(key string) {
+	c.gens.bump(key)
+	c.local.Delete(key)
+}
+
+func (c *TieredCache[V]) inc(tier string, hit bool) {
+	if c.metrics != nil {
+		c.metrics.IncTier(c.cfg.Name, tier, hit)
+	}
+}
+
+// generations counts invalidations per key. Keys share a fixed
+// number of counters, a collision only skips a local write.
+type generations struct {
+	seed   maphash.Seed
+	counts [_tieredGenerations]atomic.Uint64
+}
+
+func newGenerations() *generations {
+	return &generations{seed: maphash.MakeSeed()}
+}
+
+func (g *generations) counter(key string) *atomic.Uint64 {
+	return &g.counts[maphash.String(g.seed, key)%_tieredGenerations]
+}
+
+func (g *generations) load(key string) uint64 {
+	return g.counter(key).Load()
+}
+
+func (g *generations) bump(key string) {
+	g.counter(key).Add(1)
+}
+
+func (g *generations) bumpAll() {
+	for idx := range g.counts {
+		g.counts[idx].Add(1)
+	}
+}
@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// _ttlSetScript is SET with the TTL of a rule. An absolute TTL is
+// kept when the key already has one.
+var _ttlSetScript = NewScript("ttl_set", `
+if ARGV[3] == '1' or redis.call('PTTL', KEYS[1]) < 0 then
+	return redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+end
+return redis.call('SET', KEYS[1], ARGV[1], 'KEEPTTL')
+`)
+
+// _ttlHSetScript is HMSET with the TTL of a rule. An absolute TTL
+// is kept when the key already has one.
+var _ttlHSetScript = NewScript("ttl_hmset", `
+for i = 3, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+if ARGV[2] == '1' or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 'OK'
+`)
+
+// TTLRule sets the TTL of keys matching Pattern (glob with * and
+// ?) that are written without a TTL. The TTL is spread by up to
+// Jitter percent in both directions, so keys written together
+// don't expire together. A sliding TTL is set on every write, an
+// absolute one only when the key has none.
+type TTLRule struct {
+	Pattern string        `yaml:"pattern"`
+	TTL     time.Duration `yaml:"ttl"`
+	Jitter  float64       `yaml:"jitter"`
+	Sliding bool          `yaml:"sliding"`
+}
+
+// Next returns the TTL with jitter.
+func (rule TTLRule) Next() time.Duration {
+	ttl := rule.TTL
+	if jitter := min(rule.Jitter, 100) / 100; jitter > 0 {
+		ttl = time.Duration(float64(ttl) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+	return max(ttl, time.Millisecond)
+}
+
+// ttlRules are checked in the order of the config, the first
+// matching rule applies.
+type ttlRules []TTLRule
+
+func newTTLRules(cfg *Config) ttlRules {
+	rules := make(ttlRules, 0, len(cfg.TTLRules))
+	for _, rule := range cfg.TTLRules {
+		if rule.Pattern != "" && rule.TTL > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func (rules ttlRules) lookup(key string) (TTLRule, bool) {
+	for _, rule := range rules {
+		if matchPattern(rule.Pattern, key) {
+			return rule, true
+		}
+	}
+	return TTLRule{}, false
+}
+
+// TTLRule returns the rule applied to key written without a TTL.
+func (r *Redis) TTLRule(key string) (TTLRule, bool) {
+	return r.ttlRules.lookup(key)
+}
+
+// loadTTLScripts caches the scripts of the TTL rules, so the
+// builders can send EVALSHA.
+func (r *Redis) loadTTLScripts(ctx context.Context) error {
+	if len(r.ttlRules) == 0 {
+		return nil
+	}
+	return r.load(ctx, _ttlSetScript, _ttlHSetScript)
+}
+
+// ttlSet runs SET under rule.
+func (r *Redis) ttlSet(ctx context.Context, rule TTLRule, key, value string) error {
+	return r.Eval(ctx, _ttlSetScript, []string{key}, ttlSetArgs(rule, value)).Error()
+}
+
+// ttlSetCompleted builds SET under rule.
+func (r *Redis) ttlSetCompleted(rule TTLRule, key, value string) rueidis.Completed {
+	return _ttlSetScript.completed(r, []string{key}, ttlSetArgs(rule, value))
+}
+
+// ttlHMSet runs HMSET under rule.
+func (r *Redis) ttlHMSet(ctx context.Context, rule TTLRule, key string, kvs map[string]string) error {
+	return r.Eval(ctx, _ttlHSetScript, []string{key}, ttlHMSetArgs(rule, kvs)).Error()
+}
+
+// ttlHMSetCompleted builds HMSET under rule.
+func (r *Redis) ttlHMSetCompleted(rule TTLRule, key string, kvs map[string]string) rueidis.Completed {
+	return _ttlHSetScript.completed(r, []string{key}, ttlHMSetArgs(rule, kvs))
+}
+
+func ttlSetArgs(rule TTLRule, value string) []string {
+	return []string{value, strconv.FormatInt(rule.Next().Milliseconds(), 10), slidingArg(rule)}
+}
+
+func ttlHMSetArgs(rule TTLRule, kvs map[string]string) []string {
+	args := make([]string, 0, 2+2*len(kvs))
+	args = append(args, strconv.FormatInt(rule.Next().Milliseconds(), 10), slidingArg(rule))
+	for k, v := range kvs {
+		args = append(args, k, v)
+	}
+	return args
+}
+
+func slidingArg(rule TTLRule) string {
+	if rule.Sliding {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+type Aggregate string
+
+const (
+	AggregateSum Aggregate = "SUM"
+	AggregateMin Aggregate = "MIN"
+	AggregateMax Aggregate = "MAX"
+)
+
+// ZStoreOptions are the WEIGHTS and AGGREGATE options of
+// ZUNIONSTORE and ZINTERSTORE. Weights, when set, must match the
+// number of keys.
+type ZStoreOptions struct {
+	Weights   []float64
+	Aggregate Aggregate
+}
+
+func (r *Redis) ZUnionStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed {
+	return zstoreCompleted(r.conn.B(), "ZUNIONSTORE", destination, keys, opts)
+}
+
+// ZUnionStoreWithOptions stores the union of keys with weights
+// and aggregate in destination and returns its size.
+func (r *Redis) ZUnionStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.ZUnionStoreWithOptionsCompleted(destination, keys, opts)).ToInt64()
+	r.writeTimingAndCounter(startTime, "redis_zunionstore", err == nil)
+
+	return result, err
+}
+
+func (r *Redis) ZInterStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed {
+	return zstoreCompleted(r.conn.B(), "ZINTERSTORE", destination, keys, opts)
+}
+
+// ZInterStoreWithOptions stores the intersection of keys with
+// weights and aggregate in destination and returns its size.
+func (r *Redis) ZInterStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.ZInterStoreWithOptionsCompleted(destination, keys, opts)).ToInt64()
+	r.writeTimingAndCounter(startTime, "redis_zinterstore", err == nil)
+
+	return result, err
+}
+
+// ZRevRangeWithScores returns members from the highest score
+// with their scores.
+func (r *Redis) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]rueidis.ZScore, error) {
+	startTime := time.Now()
+	result, err := r.do(ctx, r.conn.B().Zrevrange().Key(key).Start(start).Stop(stop).Withscores().Build()).AsZScores()
+	r.writeTimingAndCounter(startTime, "redis_zrevrange", err == nil)
+
+	return result, err
+}
+
+func zstoreCompleted(b rueidis.Builder, command, destination string, keys []string, opts ZStoreOptions) rueidis.Completed {
+	cmd := b.Arbitrary(command).Keys(destination).Args(strconv.Itoa(len(keys))).Keys(keys...)
+	if len(opts.Weights) > 0 {
+		cmd = cmd.Args("WEIGHTS")
+		for _, weight := range opts.Weights {
+			cmd = cmd.Args(formatFloat(weight))
+		}
+	}
+	if opts.Aggregate != "" {
+		cmd = cmd.Args("AGGREGATE", string(opts.Aggregate))
+	}
+	return cmd.Build()
+}
+
+func (r *Multi) ZUnionStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed {
+	return r.mainConn.ZUnionStoreWithOptionsCompleted(destination, keys, opts)
+}
+
+func (r *Multi) ZUnionStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZUnionStoreWithOptions(ctx, destination, keys, opts)
+	})
+}
+
+func (r *Multi) ZInterStoreWithOptionsCompleted(destination string, keys []string, opts ZStoreOptions) rueidis.Completed {
+	return r.mainConn.ZInterStoreWithOptionsCompleted(destination, keys, opts)
+}
+
+func (r *Multi) ZInterStoreWithOptions(ctx context.Context, destination string, keys []string, opts ZStoreOptions) (int64, error) {
+	return writeAll(r, func(conn *Redis) (int64, error) {
+		return conn.ZInterStoreWithOptions(ctx, destination, keys, opts)
+	})
+}
+
+func (r *Multi) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]rueidis.ZScore, error) {
+	return readAll(ctx, r, "ZRevRangeWithScores", func(ctx context.Context, conn *Redis) ([]rueidis.ZScore, error) {
+		return conn.ZRevRangeWithScores(ctx, key, start, stop)
+	})
+}